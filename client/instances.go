@@ -18,7 +18,9 @@ package client
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 )
 
 type ListOptions struct {
@@ -31,6 +33,9 @@ type ListOptions struct {
 	Generated        *bool
 	ExportDatabaseID string
 	InternalOnly     *bool
+	State            string // Allowed values: "pending", "active", "failed", "deleting", "paused", "trashed", "truncating" (comma separated)
+	Expiring         *bool
+	ExpiresBefore    *time.Time
 }
 
 func (l *ListOptions) toQuery() (query string) {
@@ -81,6 +86,24 @@ func (l *ListOptions) toQuery() (query string) {
 		query += "&"
 	}
 
+	if l.State != "" {
+		query += "state=" + l.State + "&"
+	}
+
+	if l.Expiring != nil {
+		query += "expiring="
+		if *l.Expiring {
+			query += "true"
+		} else {
+			query += "false"
+		}
+		query += "&"
+	}
+
+	if l.ExpiresBefore != nil {
+		query += "expires_before=" + url.QueryEscape(l.ExpiresBefore.Format(time.RFC3339)) + "&"
+	}
+
 	return query[:len(query)-1]
 }
 
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"errors"
+	"net/http"
+)
+
+func (c *Client) GetJob(token string, id string) (result Job, err error) {
+	if id == "" {
+		return result, errors.New("malformed id")
+	}
+	req, err := http.NewRequest(http.MethodGet, c.baseUrl+"/jobs/"+id, nil)
+	if err != nil {
+		return result, err
+	}
+
+	req.Header.Add("Authorization", token)
+
+	return do[Job](req)
+}
@@ -21,3 +21,4 @@ import "github.com/SENERGY-Platform/analytics-serving/lib"
 type InstancesResponse = lib.InstancesResponse
 type Instance = lib.Instance
 type Instances = lib.Instances
+type Job = lib.Job
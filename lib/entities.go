@@ -16,6 +16,30 @@
 
 package lib
 
+import "time"
+
+const (
+	BulkStatusCreated = "created"
+	BulkStatusUpdated = "updated"
+	BulkStatusInvalid = "invalid"
+	BulkStatusFailed  = "failed"
+	BulkStatusSkipped = "skipped"
+	BulkStatusDeleted = "deleted"
+	BulkStatusMoved   = "moved"
+)
+
+const (
+	ApplyActionCreate = "create"
+	ApplyActionUpdate = "update"
+	ApplyActionDelete = "delete"
+	ApplyActionNone   = "none"
+)
+
+const (
+	ApplyKindExportDatabase = "export-database"
+	ApplyKindExport         = "export"
+)
+
 type Response struct {
 	Message string `json:"message,omitempty" validate:"required"`
 }
@@ -31,19 +55,122 @@ type ServingRequest struct {
 	TimePath         string                `json:"TimePath,omitempty"`
 	TimePrecision    string                `json:"TimePrecision,omitempty"`
 	Generated        bool                  `json:"generated,omitempty"`
-	Offset           string                `json:"Offset,omitempty" validate:"required"`
+	Offset           string                `json:"Offset,omitempty" validate:"required,offset"`
 	ForceUpdate      bool                  `json:"ForceUpdate,omitempty"`
 	Values           []ServingRequestValue `json:"Values,omitempty"`
 	ExportDatabaseID string                `json:"ExportDatabaseID,omitempty"`
 	TimestampFormat  string                `json:"TimestampFormat,omitempty"`
 	TimestampUnique  bool                  `json:"TimestampUnique,omitempty"`
+	ResetData        bool                  `json:"ResetData,omitempty"`
+	ExpiresAt        *time.Time            `json:"ExpiresAt,omitempty" validate:"omitempty,future"`
 }
 
 type ServingRequestValue struct {
-	Name string `json:"Name,omitempty"`
-	Type string `json:"Type,omitempty"`
-	Path string `json:"Path,omitempty"`
-	Tag  bool   `json:"Tag"`
+	Name       string `json:"Name,omitempty"`
+	Type       string `json:"Type,omitempty"`
+	Path       string `json:"Path,omitempty"`
+	Tag        bool   `json:"Tag"`
+	PrimaryKey bool   `json:"PrimaryKey,omitempty"`
+}
+
+type MappingTestRequest struct {
+	InstanceID string          `json:"InstanceID,omitempty"`
+	Request    *ServingRequest `json:"Request,omitempty"`
+	Sample     interface{}     `json:"Sample" validate:"required"`
+}
+
+type MappingTestResult struct {
+	Target string                 `json:"target,omitempty"`
+	Time   *time.Time             `json:"time,omitempty"`
+	Fields map[string]interface{} `json:"fields"`
+	Tags   map[string]interface{} `json:"tags,omitempty"`
+	Errors []string               `json:"errors,omitempty"`
+}
+
+type BulkUpdateRequest struct {
+	ID string `json:"ID" validate:"required"`
+	ServingRequest
+}
+
+type BulkResult struct {
+	Index            int                 `json:"Index"`
+	ID               string              `json:"ID,omitempty"`
+	Status           string              `json:"Status"`
+	Error            string              `json:"Error,omitempty"`
+	ValidationErrors map[string][]string `json:"ValidationErrors,omitempty"`
+	Instance         *Instance           `json:"Instance,omitempty"`
+}
+
+type Bundle struct {
+	ExportDatabases []ExportDatabaseRequest `json:"ExportDatabases"`
+	Exports         []BundleExport          `json:"Exports"`
+	// CredentialsRequired lists the export databases whose credentials are not exported and have to be set again.
+	CredentialsRequired []string `json:"CredentialsRequired,omitempty"`
+}
+
+type BundleExport struct {
+	ExportDatabase string `json:"ExportDatabase" validate:"required"`
+	ServingRequest
+}
+
+type BundleReport struct {
+	ExportDatabases []BundleReportItem `json:"ExportDatabases"`
+	Exports         []BundleReportItem `json:"Exports"`
+}
+
+type BundleReportItem struct {
+	Name   string `json:"Name"`
+	Status string `json:"Status"`
+	ID     string `json:"ID,omitempty"`
+	Reason string `json:"Reason,omitempty"`
+}
+
+type ApplyResult struct {
+	DryRun  bool          `json:"DryRun"`
+	Actions []ApplyAction `json:"Actions"`
+}
+
+type ApplyAction struct {
+	Kind    string           `json:"Kind"`
+	Action  string           `json:"Action"`
+	Name    string           `json:"Name"`
+	ID      string           `json:"ID,omitempty"`
+	Changes []RevisionChange `json:"Changes,omitempty"`
+	Applied bool             `json:"Applied"`
+	Error   string           `json:"Error,omitempty"`
+}
+
+type CloneRequest struct {
+	Name             string `json:"Name,omitempty"`
+	Filter           string `json:"Filter,omitempty"`
+	Topic            string `json:"Topic,omitempty"`
+	ExportDatabaseID string `json:"ExportDatabaseID,omitempty"`
+}
+
+type ReplayRequest struct {
+	// Offset is "earliest", "latest", a RFC3339 timestamp or a relative duration like "7d".
+	Offset       string `json:"Offset" validate:"required,offset"`
+	TruncateData bool   `json:"TruncateData,omitempty"`
+}
+
+type MoveRequest struct {
+	ExportDatabaseID string `json:"ExportDatabaseID" validate:"required"`
+	// CopyData copies existing data to the new export database in a job.
+	CopyData bool `json:"CopyData,omitempty"`
+}
+
+type Revision struct {
+	Revision  int              `json:"Revision"`
+	UserId    string           `json:"UserId"`
+	CreatedAt time.Time        `json:"CreatedAt"`
+	Request   ServingRequest   `json:"Request"`
+	Changes   []RevisionChange `json:"Changes"`
+}
+
+type RevisionChange struct {
+	Field string      `json:"Field"`
+	Old   interface{} `json:"Old"`
+	New   interface{} `json:"New"`
 }
 
 type InstancesResponse struct {
@@ -52,6 +179,32 @@ type InstancesResponse struct {
 	Instances Instances `json:"instances,omitempty"`
 }
 
+const (
+	ExportDatabaseCheckConnection  = "connection"
+	ExportDatabaseCheckExtension   = "extension"
+	ExportDatabaseCheckFilterTopic = "filter-topic"
+)
+
+type ExportDatabaseCheck struct {
+	OK        bool                      `json:"OK"`
+	CheckedAt time.Time                 `json:"CheckedAt"`
+	Checks    []ExportDatabaseCheckItem `json:"Checks"`
+}
+
+type ExportDatabaseCheckItem struct {
+	Name    string `json:"Name"`
+	OK      bool   `json:"OK"`
+	Skipped bool   `json:"Skipped,omitempty"`
+	Error   string `json:"Error,omitempty"`
+}
+
+type DependentExport struct {
+	ID     string `json:"ID"`
+	Name   string `json:"Name"`
+	UserId string `json:"UserId"`
+	State  string `json:"State"`
+}
+
 type ExportDatabaseRequest struct {
 	Name          string `json:"Name" validate:"required"`
 	Description   string `json:"Description"`
@@ -60,4 +213,31 @@ type ExportDatabaseRequest struct {
 	Url           string `json:"Url" validate:"required"`
 	EwFilterTopic string `json:"EwFilterTopic" validate:"required"`
 	Public        bool   `json:"Public"`
+	Org           string `json:"Org,omitempty"`
+	Bucket        string `json:"Bucket,omitempty"`
+	// Credentials are write-only and kept if omitted on update, an empty object removes them.
+	Credentials *Credentials `json:"Credentials,omitempty"`
+}
+
+type Credentials struct {
+	Username string `json:"Username,omitempty"`
+	Password string `json:"Password,omitempty"`
+	Token    string `json:"Token,omitempty"`
+}
+
+func (c Credentials) IsEmpty() bool {
+	return c.Username == "" && c.Password == "" && c.Token == ""
+}
+
+// Merge sets all fields of c that are given in other.
+func (c *Credentials) Merge(other Credentials) {
+	if other.Username != "" {
+		c.Username = other.Username
+	}
+	if other.Password != "" {
+		c.Password = other.Password
+	}
+	if other.Token != "" {
+		c.Token = other.Token
+	}
 }
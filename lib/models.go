@@ -22,6 +22,31 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	InstanceStatePending  = "pending"
+	InstanceStateActive   = "active"
+	InstanceStateFailed   = "failed"
+	InstanceStateDeleting = "deleting"
+	InstanceStatePaused   = "paused"
+	InstanceStateTrashed  = "trashed"
+	// InstanceStateTruncating marks a replay waiting for the removal of stored data.
+	InstanceStateTruncating = "truncating"
+)
+
+const (
+	JobTypeCreate = "create"
+	JobTypeUpdate = "update"
+	JobTypeDelete = "delete"
+	JobTypeMove   = "move"
+)
+
+const (
+	JobStatePending = "pending"
+	JobStateRunning = "running"
+	JobStateDone    = "done"
+	JobStateFailed  = "failed"
+)
+
 type Instances []Instance
 
 type Instance struct {
@@ -47,16 +72,32 @@ type Instance struct {
 	TimestampFormat  string         `gorm:"type:varchar(255)"`
 	TimestampUnique  bool           `gorm:"type:bool;DEFAULT:false"`
 	Values           []Value        `gorm:"foreignkey:InstanceID;association_foreignkey:ID"`
+	State            string         `gorm:"type:varchar(32);DEFAULT:'active'"`
+	StateChangedAt   *time.Time
+	LastError        string `gorm:"type:text"`
+	LastErrorAt      *time.Time
+	TrashedAt        *time.Time
+	ExpiresAt        *time.Time `gorm:"index"`
+	ExpiryNotifiedAt *time.Time
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 }
 
+// InfluxBucket returns the bucket influxdb2 exports write to.
+func (instance Instance) InfluxBucket() string {
+	if instance.ExportDatabase.Bucket != "" {
+		return instance.ExportDatabase.Bucket
+	}
+	return instance.Database
+}
+
 type Value struct {
 	InstanceID uuid.UUID `gorm:"type:char(36)"`
 	Name       string    `gorm:"type:varchar(255)"`
 	Type       string    `gorm:"type:varchar(255)"`
 	Path       string    `gorm:"type:varchar(255)"`
 	Tag        bool      `gorm:"type:bool;DEFAULT:false"`
+	PrimaryKey bool      `gorm:"type:bool;DEFAULT:false"`
 }
 
 type ExportDatabase struct {
@@ -69,4 +110,39 @@ type ExportDatabase struct {
 	EwFilterTopic string `gorm:"type:varchar(255)"`
 	UserId        string `gorm:"type:varchar(255)"`
 	Public        bool   `gorm:"type:bool;DEFAULT:false"`
+	Org           string `gorm:"type:varchar(255)"`
+	// Bucket of influxdb2 exports, defaults to the database of the export.
+	Bucket string `gorm:"type:varchar(255)"`
+	// Check is the connectivity report of the latest create or update, it is not persisted.
+	Check *ExportDatabaseCheck `gorm:"-" json:"Check,omitempty"`
+	// Credentials are stored encrypted in a separate table and never returned.
+	Credentials *Credentials `gorm:"-" json:"-"`
+	// DeletedAt marks a removed export database that is kept until no export references it anymore.
+	DeletedAt *time.Time `gorm:"index" json:"-"`
+}
+
+type Job struct {
+	ID         uuid.UUID `gorm:"primary_key;type:char(36);column:id"`
+	Type       string    `gorm:"type:varchar(32)"`
+	State      string    `gorm:"type:varchar(32);index"`
+	Progress   int
+	UserId     string     `gorm:"type:varchar(255);index"`
+	InstanceID string     `gorm:"type:char(36)"`
+	Request    string     `gorm:"type:mediumtext" json:"-"`
+	Error      string     `gorm:"type:text"`
+	WorkerId   string     `gorm:"type:varchar(64)" json:"-"`
+	LeaseUntil *time.Time `gorm:"index" json:"-"`
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type InstanceRevision struct {
+	InstanceID uuid.UUID `gorm:"primary_key;type:char(36);auto_increment:false"`
+	Revision   int       `gorm:"primary_key;auto_increment:false"`
+	UserId     string    `gorm:"type:varchar(255)"`
+	Request    string    `gorm:"type:mediumtext"`
+	Changes    string    `gorm:"type:mediumtext"`
+	CreatedAt  time.Time
 }
@@ -32,6 +32,7 @@ import (
 	api_doc "github.com/SENERGY-Platform/analytics-serving/pkg/api-doc"
 	ew_api "github.com/SENERGY-Platform/analytics-serving/pkg/apis/ew-api"
 	import_deploy_api "github.com/SENERGY-Platform/analytics-serving/pkg/apis/import-deploy-api"
+	notification_api "github.com/SENERGY-Platform/analytics-serving/pkg/apis/notification-api"
 	pipeline_api "github.com/SENERGY-Platform/analytics-serving/pkg/apis/pipeline-api"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
@@ -77,11 +78,23 @@ func main() {
 	m := db.NewMigration(db.GetDB(), cfg.MigrationInfo)
 	m.Migrate()
 	err = m.TmpMigrate()
+	if err == nil {
+		err = db.MigrateOffsets()
+	}
 	if err != nil {
 		util.Logger.Error("failed to migrate", "error", err)
 		ec = 1
 		return
 	}
+	err = db.InitCredentials(cfg.Credentials)
+	if err == nil {
+		err = db.MigrateCredentials()
+	}
+	if err != nil {
+		util.Logger.Error("failed to init export-database credentials", "error", err)
+		ec = 1
+		return
+	}
 
 	api_doc.PublishAsyncapiDoc(cfg.ApiDocsProviderBaseUrl)
 
@@ -102,8 +115,8 @@ func main() {
 			BatchSize:   1,
 			Balancer:    &kafka.Hash{},
 		}
+		wg.Add(1)
 		go func() {
-			wg.Add(1)
 			<-ctx.Done()
 			_ = kafkaProducer.Close()
 			util.Logger.Info("closed kafka producer connection")
@@ -115,8 +128,8 @@ func main() {
 		if err != nil {
 			return
 		}
+		wg.Add(1)
 		go func() {
-			wg.Add(1)
 			<-ctx.Done()
 			_ = kafkaConn.Close()
 			util.Logger.Info("closed kafka connection")
@@ -133,14 +146,21 @@ func main() {
 		if err != nil {
 			return
 		}
+		wg.Add(1)
 		go func() {
-			wg.Add(1)
 			<-ctx.Done()
 			_ = kafkaControllerConn.Close()
 			util.Logger.Info("closed kafka controller connection")
 			wg.Done()
 		}()
-		driver = ew_api.NewExportWorker(cfg.Kafka, &kafkaProducer, kafkaConn, kafkaControllerConn)
+		exportWorker := ew_api.NewExportWorker(cfg.Kafka, &kafkaProducer, kafkaConn, kafkaControllerConn)
+		err = exportWorker.StartOutboxRelay(ctx, wg, cfg.Outbox)
+		if err != nil {
+			util.Logger.Error("failed to start outbox relay", "error", err)
+			ec = 1
+			return
+		}
+		driver = exportWorker
 	}
 
 	go func() {
@@ -154,9 +174,17 @@ func main() {
 	var imp service.ImportDeployService
 	imp = import_deploy_api.NewImportDeployApi(cfg.ImportDeployApiUrl)
 
+	var notifier service.NotificationService
+	if cfg.NotificationUrl != "" {
+		notifier = notification_api.NewNotificationApi(cfg.NotificationUrl)
+	}
+
 	var influx service.Influx
 	influx = service.NewInflux(cfg.InfluxConfig, ctx, wg)
 
+	var timescale service.Timescale
+	timescale = service.NewTimescale(cfg.TimescaleConfig, ctx, wg)
+
 	var permV2 permV2Client.Client
 	if cfg.PermissionV2Url == "mock" {
 		util.Logger.Debug("using mock permissions")
@@ -165,7 +193,7 @@ func main() {
 		permV2 = permV2Client.New(cfg.PermissionV2Url)
 	}
 
-	httpHandler, err := api.CreateServer(cfg, &driver, &pipeline, &imp, &permV2, &influx)
+	httpHandler, err := api.CreateServer(cfg, &driver, &pipeline, &imp, &notifier, &permV2, &influx, &timescale, ctx, wg)
 	if err != nil {
 		util.Logger.Error("error creating http engine", "error", err)
 		ec = 1
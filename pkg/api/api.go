@@ -17,11 +17,13 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
@@ -47,18 +49,42 @@ func CreateServer(cfg *config.Config,
 	driver *service.Driver,
 	pipeline *service.PipelineApiService,
 	imp *service.ImportDeployService,
+	notifier *service.NotificationService,
 	permV2 *permV2Client.Client,
-	influx *service.Influx) (r *gin.Engine, err error) {
+	influx *service.Influx,
+	timescale *service.Timescale,
+	ctx context.Context,
+	wg *sync.WaitGroup) (r *gin.Engine, err error) {
 
 	cleanupWait, err := time.ParseDuration(cfg.CleanupConfig.WaitDuration)
+	if err != nil {
+		return
+	}
+	purgeDelay, err := time.ParseDuration(cfg.CleanupConfig.PurgeDelay)
+	if err != nil {
+		return
+	}
+	expiryWarning, err := time.ParseDuration(cfg.CleanupConfig.ExpiryWarning)
+	if err != nil {
+		return
+	}
 	serv, err := service.NewServing(*driver,
 		*influx,
+		*timescale,
 		*pipeline,
 		*imp,
+		*notifier,
 		cfg.ExportDatabaseIdPrefix,
 		*permV2,
 		cfg.CleanupConfig.Cron,
 		cleanupWait,
+		cfg.CleanupConfig.PurgeCron,
+		purgeDelay,
+		cfg.CleanupConfig.ExpiryCron,
+		expiryWarning,
+		cfg.JobConfig,
+		ctx,
+		wg,
 	)
 	if err != nil {
 		return
@@ -19,8 +19,10 @@ package api
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/SENERGY-Platform/analytics-serving/lib"
@@ -28,6 +30,7 @@ import (
 	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
+	"sigs.k8s.io/yaml"
 )
 
 // postNewServingInstance godoc
@@ -38,7 +41,9 @@ import (
 // @Produce	json
 // @Security Bearer
 // @Param request body lib.ServingRequest true "request data"
+// @Param async query bool false "run as job and return 202 with the job"
 // @Success	201 {object} lib.Instance "export"
+// @Success	202 {object} lib.Job "job"
 // @Failure	400 {object} map[string]map[string][]string "error data"
 // @Failure	500
 // @Router /instance [post]
@@ -57,6 +62,11 @@ func postNewServingInstance(serv *service.Serving) (string, string, gin.HandlerF
 			c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": errs})
 			return
 		}
+		if c.Query("async") == "true" {
+			job, err := serv.SubmitCreateJob(request, c.GetString(UserIdKey), c.GetHeader("Authorization"))
+			handleJobSubmit(c, "could not submit serving instance creation", job, err)
+			return
+		}
 		instance, err := serv.CreateInstance(request, c.GetString(UserIdKey), c.GetHeader("Authorization"))
 		if err != nil {
 			util.Logger.Error("could not create serving instance", "error", err)
@@ -67,16 +77,204 @@ func postNewServingInstance(serv *service.Serving) (string, string, gin.HandlerF
 	}
 }
 
+// getServingInstanceBundle godoc
+// @Summary Export bundle
+// @Description Serialize the exports and export databases owned by the user without ids, shared exports are not included. Exports reference export databases by name. Credentials are not exported, affected export databases are listed in CredentialsRequired.
+// @Tags Export
+// @Produce	json,application/yaml
+// @Security Bearer
+// @Param format query string false "json (default) or yaml"
+// @Success	200 {object} lib.Bundle "bundle"
+// @Failure	500
+// @Router /instance/export-bundle [get]
+func getServingInstanceBundle(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodGet, "/instance/export-bundle", func(c *gin.Context) {
+		bundle, err := serv.ExportBundle(c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			util.Logger.Error("could not export bundle", "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		if c.Query("format") == "yaml" {
+			b, err := yaml.Marshal(bundle)
+			if err != nil {
+				util.Logger.Error("could not export bundle", "error", err)
+				_ = c.Error(errors.New(MessageSomethingWrong))
+				return
+			}
+			c.Data(http.StatusOK, "application/yaml", b)
+			return
+		}
+		c.JSON(http.StatusOK, bundle)
+	}
+}
+
+// postServingInstanceBundle godoc
+// @Summary Import bundle
+// @Description Create the export databases and exports of a bundle. Export databases the user can access and exports the user owns with the same name are skipped.
+// @Tags Export
+// @Accept json,application/yaml
+// @Produce	json
+// @Security Bearer
+// @Param request body lib.Bundle true "bundle"
+// @Success	200 {object} lib.BundleReport "created and skipped entries"
+// @Failure	400 {object} map[string]map[string][]string "error data"
+// @Failure	500
+// @Router /instance/import-bundle [post]
+func postServingInstanceBundle(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/import-bundle", func(c *gin.Context) {
+		bundle, ok := bindBundle(c)
+		if !ok {
+			return
+		}
+		report, err := serv.ImportBundle(bundle, c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			util.Logger.Error("could not import bundle", "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// postApply godoc
+// @Summary Apply exports
+// @Description Reconcile exports with a desired set given as bundle. Exports are matched by name. Own exports selected by the query filters that are not part of the bundle are deleted, without filters only if prune is set. Missing export databases are created, export databases are never deleted. Relative offsets only position created exports, existing exports keep their offset.
+// @Tags Export
+// @Accept json,application/yaml
+// @Produce	json
+// @Security Bearer
+// @Param request body lib.Bundle true "desired exports"
+// @Param dry_run query bool false "only compute the actions"
+// @Param prune query bool false "delete own exports that are not part of the bundle, implied by the select filters"
+// @Param search query string false "select exports by search"
+// @Param generated query string false "select exports by generated"
+// @Param export_database_id query string false "select exports by export database"
+// @Param internal_only query string false "select exports of internal export databases"
+// @Success	200 {object} lib.ApplyResult "actions"
+// @Failure	400 {object} map[string]map[string][]string "error data"
+// @Failure	500
+// @Router /apply [post]
+func postApply(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/apply", func(c *gin.Context) {
+		bundle, ok := bindBundle(c)
+		if !ok {
+			return
+		}
+		args := map[string][]string{}
+		for arg, value := range c.Request.URL.Query() {
+			if arg == "search" || arg == "generated" || arg == "export_database_id" || arg == "internal_only" {
+				args[arg] = value
+			}
+		}
+		result, err := serv.Apply(bundle, args, c.Query("prune") == "true", c.Query("dry_run") == "true", c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidExport) {
+				c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": {"Exports": {err.Error()}}})
+				return
+			}
+			util.Logger.Error("could not apply exports", "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// postServingInstancePreview godoc
+// @Summary Preview export
+// @Description Render the filter the export worker would receive for an export, without creating it.
+// @Tags Export
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param request body lib.ServingRequest true "request data"
+// @Success	200 {object} map[string]interface{} "filter message"
+// @Failure	400 {object} map[string]map[string][]string "error data"
+// @Failure	500
+// @Router /instance/preview [post]
+func postServingInstancePreview(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/preview", func(c *gin.Context) {
+		var request lib.ServingRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			util.Logger.Error(MessageParseError, "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+
+		validated, errs := ValidateInputs(request)
+
+		if !validated {
+			c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": errs})
+			return
+		}
+		preview, err := serv.PreviewInstance(request, c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidExport) {
+				c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": {"export": {err.Error()}}})
+				return
+			}
+			util.Logger.Error("could not preview serving instance", "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		c.JSON(http.StatusOK, preview)
+	}
+}
+
+// postServingInstanceMappingTest godoc
+// @Summary Test export mapping
+// @Description Apply an existing export or an export request to a sample message and return the resulting row or point.
+// @Tags Export
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param request body lib.MappingTestRequest true "instance id or export request and sample message"
+// @Success	200 {object} lib.MappingTestResult "mapping result"
+// @Failure	400 {object} map[string]map[string][]string "error data"
+// @Failure	500
+// @Router /instance/test-mapping [post]
+func postServingInstanceMappingTest(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/test-mapping", func(c *gin.Context) {
+		var request lib.MappingTestRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			util.Logger.Error(MessageParseError, "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+
+		validated, errs := ValidateInputs(request)
+
+		if !validated {
+			c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": errs})
+			return
+		}
+		result, err := serv.TestMapping(request, c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidExport) {
+				c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": {"export": {err.Error()}}})
+				return
+			}
+			util.Logger.Error("could not test serving instance mapping", "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
 // putNewServingInstance godoc
 // @Summary Update export
-// @Description Update an export.
+// @Description Update an export. Stored data is kept unless ResetData is set.
 // @Tags Export
 // @Accept json
 // @Produce	json
 // @Security Bearer
 // @Param id path string true "export id"
 // @Param request body lib.ServingRequest true "request data"
+// @Param async query bool false "run as job and return 202 with the job"
 // @Success	200 {object} lib.Instance "export"
+// @Success	202 {object} lib.Job "job"
 // @Failure	400 {object} map[string]map[string][]string "error data"
 // @Failure	500
 // @Router /instance/{id} [put]
@@ -95,6 +293,11 @@ func putNewServingInstance(serv *service.Serving) (string, string, gin.HandlerFu
 			c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": valErrs})
 			return
 		}
+		if c.Query("async") == "true" {
+			job, err := serv.SubmitUpdateJob(c.Param("id"), c.GetString(UserIdKey), request, c.GetHeader("Authorization"))
+			handleJobSubmit(c, "could not submit serving instance update", job, err)
+			return
+		}
 		instance, errs := serv.UpdateInstance(c.Param("id"), c.GetString(UserIdKey), request, c.GetHeader("Authorization"))
 		if len(errs) > 0 {
 			util.Logger.Error("could not update serving instance", "error", errs)
@@ -105,6 +308,247 @@ func putNewServingInstance(serv *service.Serving) (string, string, gin.HandlerFu
 	}
 }
 
+// postServingInstancePause godoc
+// @Summary Pause export
+// @Description Stop an export without deleting its configuration or stored data.
+// @Tags Export
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "export id"
+// @Success	200 {object} lib.Instance "export"
+// @Failure	404
+// @Failure	409 {object} map[string]string "error message"
+// @Failure	500
+// @Router /instance/{id}/pause [post]
+func postServingInstancePause(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/:id/pause", func(c *gin.Context) {
+		instance, err := serv.PauseInstance(c.Param("id"), c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			handleInstanceStateError(c, "could not pause serving instance", err)
+			return
+		}
+		c.JSON(http.StatusOK, instance)
+	}
+}
+
+// postServingInstanceResume godoc
+// @Summary Resume export
+// @Description Resume a paused or failed export.
+// @Tags Export
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "export id"
+// @Success	200 {object} lib.Instance "export"
+// @Failure	404
+// @Failure	409 {object} map[string]string "error message"
+// @Failure	500
+// @Router /instance/{id}/resume [post]
+func postServingInstanceResume(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/:id/resume", func(c *gin.Context) {
+		instance, err := serv.ResumeInstance(c.Param("id"), c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			handleInstanceStateError(c, "could not resume serving instance", err)
+			return
+		}
+		c.JSON(http.StatusOK, instance)
+	}
+}
+
+// postServingInstanceClone godoc
+// @Summary Clone export
+// @Description Create a new export from an existing one. Name, filter, topic and export database can be overridden.
+// @Tags Export
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "export id"
+// @Param request body lib.CloneRequest false "overrides"
+// @Success	201 {object} lib.Instance "export"
+// @Failure	404
+// @Failure	500
+// @Router /instance/{id}/clone [post]
+func postServingInstanceClone(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/:id/clone", func(c *gin.Context) {
+		var request lib.CloneRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&request); err != nil {
+				util.Logger.Error(MessageParseError, "error", err)
+				_ = c.Error(errors.New(MessageSomethingWrong))
+				return
+			}
+		}
+		instance, err := serv.CloneInstance(c.Param("id"), request, c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			handleInstanceStateError(c, "could not clone serving instance", err)
+			return
+		}
+		c.JSON(http.StatusCreated, instance)
+	}
+}
+
+// postServingInstanceRestore godoc
+// @Summary Restore export
+// @Description Take an export out of the trash and resume it. Exports whose export database was removed have to be moved first.
+// @Tags Export
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "export id"
+// @Success	200 {object} lib.Instance "export"
+// @Failure	404
+// @Failure	409 {object} map[string]string "error message"
+// @Failure	500
+// @Router /instance/{id}/restore [post]
+func postServingInstanceRestore(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/:id/restore", func(c *gin.Context) {
+		instance, err := serv.RestoreInstance(c.Param("id"), c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			handleInstanceStateError(c, "could not restore serving instance", err)
+			return
+		}
+		c.JSON(http.StatusOK, instance)
+	}
+}
+
+// postServingInstanceReplay godoc
+// @Summary Replay export
+// @Description Restart consumption of an export at the given offset. Existing data can be removed first, the export is in state truncating until then.
+// @Tags Export
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "export id"
+// @Param request body lib.ReplayRequest true "replay options"
+// @Success	200 {object} lib.Instance "export"
+// @Failure	400 {object} map[string]map[string][]string "validation errors"
+// @Failure	404
+// @Failure	409 {object} map[string]string "error message"
+// @Failure	500
+// @Router /instance/{id}/replay [post]
+func postServingInstanceReplay(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/:id/replay", func(c *gin.Context) {
+		var request lib.ReplayRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			util.Logger.Error(MessageParseError, "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		validated, errs := ValidateInputs(request)
+		if !validated {
+			c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": errs})
+			return
+		}
+		instance, err := serv.ReplayInstance(c.Param("id"), request, c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			handleInstanceStateError(c, "could not replay serving instance", err)
+			return
+		}
+		c.JSON(http.StatusOK, instance)
+	}
+}
+
+// postServingInstanceMove godoc
+// @Summary Move export
+// @Description Move an export to another export database of a compatible type. Existing data stays in the old export
+// @Description database unless CopyData is set, which copies it in a job whose progress is reported.
+// @Tags Export
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "export id"
+// @Param request body lib.MoveRequest true "move options"
+// @Success	200 {object} lib.Instance "export"
+// @Success	202 {object} lib.Job "job, if data is copied"
+// @Failure	400 {object} map[string]map[string][]string "validation errors"
+// @Failure	404
+// @Failure	409 {object} map[string]string "error message"
+// @Failure	500
+// @Router /instance/{id}/move [post]
+func postServingInstanceMove(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/:id/move", func(c *gin.Context) {
+		var request lib.MoveRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			util.Logger.Error(MessageParseError, "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		validated, errs := ValidateInputs(request)
+		if !validated {
+			c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": errs})
+			return
+		}
+		if request.CopyData {
+			job, err := serv.SubmitMoveJob(c.Param("id"), request, c.GetString(UserIdKey), c.GetHeader("Authorization"))
+			if errors.Is(err, service.ErrInvalidExport) {
+				c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": {"ExportDatabaseID": {err.Error()}}})
+				return
+			}
+			handleJobSubmit(c, "could not submit serving instance move", job, err)
+			return
+		}
+		instance, err := serv.MoveInstance(c.Param("id"), request, c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidExport) {
+				c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": {"ExportDatabaseID": {err.Error()}}})
+				return
+			}
+			handleInstanceStateError(c, "could not move serving instance", err)
+			return
+		}
+		c.JSON(http.StatusOK, instance)
+	}
+}
+
+// getServingInstanceRevisions godoc
+// @Summary Get export revisions
+// @Description List all accepted definitions of an export with their changes, newest first.
+// @Tags Export
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "export id"
+// @Success	200 {array} lib.Revision "revisions"
+// @Failure	404
+// @Failure	500
+// @Router /instance/{id}/revisions [get]
+func getServingInstanceRevisions(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodGet, "/instance/:id/revisions", func(c *gin.Context) {
+		revisions, err := serv.GetRevisions(c.Param("id"), c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if err != nil {
+			handleInstanceStateError(c, "could not get serving instance revisions", err)
+			return
+		}
+		c.JSON(http.StatusOK, revisions)
+	}
+}
+
+// postServingInstanceRevisionRestore godoc
+// @Summary Restore export revision
+// @Description Apply a previous definition of an export. The restore is recorded as new revision, an expiry that has passed is removed.
+// @Tags Export
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "export id"
+// @Param rev path int true "revision"
+// @Success	200 {object} lib.Instance "export"
+// @Failure	400
+// @Failure	404
+// @Failure	500
+// @Router /instance/{id}/revisions/{rev}/restore [post]
+func postServingInstanceRevisionRestore(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instance/:id/revisions/:rev/restore", func(c *gin.Context) {
+		rev, err := strconv.Atoi(c.Param("rev"))
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		instance, errs := serv.RestoreRevision(c.Param("id"), rev, c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if len(errs) > 0 {
+			handleInstanceStateError(c, "could not restore serving instance revision", errors.Join(errs...))
+			return
+		}
+		c.JSON(http.StatusOK, instance)
+	}
+}
+
 // getServingInstance godoc
 // @Summary Get export
 // @Description Retrieve an export.
@@ -146,6 +590,10 @@ func getServingInstance(serv *service.Serving) (string, string, gin.HandlerFunc)
 // @Param generated query string false "generated"
 // @Param export_database_id query string false "export_database_id"
 // @Param internal_only query string false "internal_only"
+// @Param expiring query string false "only exports with (true) or without (false) expiry"
+// @Param expires_before query string false "RFC3339 timestamp"
+// @Param expires_after query string false "RFC3339 timestamp"
+// @Param state query string false "comma separated states (pending, active, failed, deleting, paused, trashed, truncating), trashed exports are only listed if requested"
 // @Success	200 {array} lib.Instance "exports"
 // @Failure	500
 // @Router /instance [get]
@@ -168,13 +616,15 @@ func getServingInstances(serv *service.Serving) (string, string, gin.HandlerFunc
 
 // deleteServingInstance godoc
 // @Summary Delete export
-// @Description Remove an export.
+// @Description Delete an export including its stored data. The data is removed in the background once the export stopped writing. If a purge delay is configured, the export is moved to the trash and purged after the delay.
 // @Tags Export
 // @Accept json
 // @Produce	json
 // @Security Bearer
 // @Param id path string true "export id"
+// @Param async query bool false "run as job and return 202 with the job"
 // @Success	200
+// @Success	202 {object} lib.Job "job"
 // @Success	204
 // @Failure	207 {object} map[string]string ""
 // @Failure	404
@@ -182,6 +632,11 @@ func getServingInstances(serv *service.Serving) (string, string, gin.HandlerFunc
 // @Router /instance/{id} [delete]
 func deleteServingInstance(serv *service.Serving) (string, string, gin.HandlerFunc) {
 	return http.MethodDelete, "/instance/:id", func(c *gin.Context) {
+		if c.Query("async") == "true" {
+			job, err := serv.SubmitDeleteJob(c.Param("id"), c.GetString(UserIdKey), c.GetHeader("Authorization"))
+			handleJobSubmit(c, "could not submit serving instance deletion", job, err)
+			return
+		}
 		deleted, errs := serv.DeleteInstanceForUser(c.Param("id"), c.GetString(UserIdKey), c.GetHeader("Authorization"))
 		if len(errs) > 0 {
 			util.Logger.Error("could not delete serving instance", "error", errs)
@@ -198,9 +653,89 @@ func deleteServingInstance(serv *service.Serving) (string, string, gin.HandlerFu
 	}
 }
 
+// postNewServingInstances godoc
+// @Summary Create exports
+// @Description Create multiple exports. Every export is created independently, the result lists the outcome per request.
+// @Tags Export
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param request body []lib.ServingRequest true "request data"
+// @Success	201 {array} lib.BulkResult "results"
+// @Success	207 {array} lib.BulkResult "results"
+// @Failure	500
+// @Router /instances [post]
+func postNewServingInstances(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/instances", func(c *gin.Context) {
+		var request []lib.ServingRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			util.Logger.Error(MessageParseError, "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		results := make([]lib.BulkResult, len(request))
+		var valid []lib.ServingRequest
+		var indices []int
+		for i, item := range request {
+			validated, errs := ValidateInputs(item)
+			if !validated {
+				results[i] = lib.BulkResult{Index: i, Status: lib.BulkStatusInvalid, ValidationErrors: errs}
+				continue
+			}
+			valid = append(valid, item)
+			indices = append(indices, i)
+		}
+		for i, result := range serv.CreateInstances(valid, c.GetString(UserIdKey), c.GetHeader("Authorization")) {
+			result.Index = indices[i]
+			results[result.Index] = result
+		}
+		c.JSON(bulkStatus(results, http.StatusCreated), results)
+	}
+}
+
+// putNewServingInstances godoc
+// @Summary Update exports
+// @Description Update multiple exports. Every export is updated independently, the result lists the outcome per request.
+// @Tags Export
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param request body []lib.BulkUpdateRequest true "export ids and request data"
+// @Success	200 {array} lib.BulkResult "results"
+// @Success	207 {array} lib.BulkResult "results"
+// @Failure	500
+// @Router /instances [put]
+func putNewServingInstances(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPut, "/instances", func(c *gin.Context) {
+		var request []lib.BulkUpdateRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			util.Logger.Error(MessageParseError, "error", err)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		results := make([]lib.BulkResult, len(request))
+		var valid []lib.BulkUpdateRequest
+		var indices []int
+		for i, item := range request {
+			validated, errs := ValidateInputs(item)
+			if !validated {
+				results[i] = lib.BulkResult{Index: i, ID: item.ID, Status: lib.BulkStatusInvalid, ValidationErrors: errs}
+				continue
+			}
+			valid = append(valid, item)
+			indices = append(indices, i)
+		}
+		for i, result := range serv.UpdateInstances(valid, c.GetString(UserIdKey), c.GetHeader("Authorization")) {
+			result.Index = indices[i]
+			results[result.Index] = result
+		}
+		c.JSON(bulkStatus(results, http.StatusOK), results)
+	}
+}
+
 // deleteServingInstances godoc
 // @Summary Delete exports
-// @Description Remove multiple exports.
+// @Description Delete multiple exports, they are moved to the trash if a purge delay is configured.
 // @Tags Export
 // @Accept json
 // @Produce	json
@@ -237,6 +772,28 @@ func deleteServingInstances(serv *service.Serving) (string, string, gin.HandlerF
 	}
 }
 
+// getJob godoc
+// @Summary Get job
+// @Description Retrieve the state, progress and error of an asynchronous export operation.
+// @Tags Job
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "job id"
+// @Success	200 {object} lib.Job "job"
+// @Failure	404
+// @Failure	500
+// @Router /jobs/{id} [get]
+func getJob(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodGet, "/jobs/:id", func(c *gin.Context) {
+		job, err := serv.GetJob(c.Param("id"), c.GetString(UserIdKey))
+		if err != nil {
+			handleInstanceStateError(c, "could not get job", err)
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	}
+}
+
 // getServingInstancesAdmin godoc
 // @Summary Get exports
 // @Description Get all exports
@@ -261,7 +818,7 @@ func getServingInstancesAdmin(serv *service.Serving) (string, string, gin.Handle
 
 // deleteServingInstanceAdmin godoc
 // @Summary Delete export
-// @Description Remove an export.
+// @Description Delete an export, it is moved to the trash if a purge delay is configured.
 // @Tags Export
 // @Produce	json,plain
 // @Security Bearer
@@ -309,7 +866,7 @@ func deleteServingInstanceAdmin(serv *service.Serving) (string, string, gin.Hand
 func getExportDatabases(serv *service.Serving) (string, string, gin.HandlerFunc) {
 	return http.MethodGet, "/databases", func(c *gin.Context) {
 		args := c.Request.URL.Query()
-		databases, errs := serv.GetExportDatabases(c.GetString(UserIdKey), args)
+		databases, errs := serv.GetExportDatabases(c.GetString(UserIdKey), args, c.GetHeader("Authorization"))
 		if len(errs) > 0 {
 			util.Logger.Error("could not get export databases", "error", errs)
 			_ = c.Error(errors.New(MessageSomethingWrong))
@@ -333,7 +890,7 @@ func getExportDatabases(serv *service.Serving) (string, string, gin.HandlerFunc)
 // @Router /databases/{id} [get]
 func getExportDatabase(serv *service.Serving) (string, string, gin.HandlerFunc) {
 	return http.MethodGet, "/databases/:id", func(c *gin.Context) {
-		database, errs := serv.GetExportDatabase(c.Param("id"), c.GetString(UserIdKey))
+		database, errs := serv.GetExportDatabase(c.Param("id"), c.GetString(UserIdKey), c.GetHeader("Authorization"))
 		if len(errs) > 0 {
 			for _, err := range errs {
 				if gorm.IsRecordNotFoundError(err) {
@@ -351,7 +908,7 @@ func getExportDatabase(serv *service.Serving) (string, string, gin.HandlerFunc)
 
 // postExportDatabase godoc
 // @Summary Create database
-// @Description Create an export database.
+// @Description Create an export database. The connectivity report of the target is returned as Check.
 // @Tags Export Database
 // @Accept json
 // @Produce	json
@@ -379,6 +936,9 @@ func postExportDatabase(serv *service.Serving) (string, string, gin.HandlerFunc)
 
 		database, errs := serv.CreateExportDatabase("", request, c.GetString(UserIdKey))
 		if len(errs) > 0 {
+			if unsupportedDatabaseType(c, errs) || credentialsKeyMissing(c, errs) {
+				return
+			}
 			util.Logger.Error("could not create export database", "error", errs)
 			_ = c.Error(errors.New(MessageSomethingWrong))
 			return
@@ -389,7 +949,7 @@ func postExportDatabase(serv *service.Serving) (string, string, gin.HandlerFunc)
 
 // putExportDatabase godoc
 // @Summary Update database
-// @Description Update an export database.
+// @Description Update an export database. The connectivity report of the target is returned as Check.
 // @Tags Export Database
 // @Accept json
 // @Produce	json
@@ -417,8 +977,11 @@ func putExportDatabase(serv *service.Serving) (string, string, gin.HandlerFunc)
 			return
 		}
 
-		database, errs := serv.UpdateExportDatabase(c.Param("id"), request, c.GetString(UserIdKey))
+		database, errs := serv.UpdateExportDatabase(c.Param("id"), request, c.GetString(UserIdKey), c.GetHeader("Authorization"))
 		if len(errs) > 0 {
+			if unsupportedDatabaseType(c, errs) || credentialsKeyMissing(c, errs) {
+				return
+			}
 			for _, err := range errs {
 				if gorm.IsRecordNotFoundError(err) {
 					c.Status(http.StatusNotFound)
@@ -435,26 +998,46 @@ func putExportDatabase(serv *service.Serving) (string, string, gin.HandlerFunc)
 
 // deleteExportDatabase godoc
 // @Summary Delete database
-// @Description Remove an export database.
+// @Description Remove an export database. Fails with 409 and the readable dependent exports if the database is still in use,
+// @Description unless they are deleted with cascade or moved to a compatible database with move_to. Dependent exports are
+// @Description checked before any is changed, if some fail afterwards the 409 response reports the result per export.
+// @Description Cascade deletes exports like the export delete endpoint, exports in the trash can be restored after moving them to another database.
 // @Tags Export Database
 // @Produce	json
 // @Security Bearer
 // @Param id path string true "database id"
+// @Param cascade query bool false "delete dependent exports"
+// @Param move_to query string false "id of the export database dependent exports are moved to"
 // @Success	200
 // @Failure	400 {object} map[string]string "error message"
 // @Failure	404 {object} map[string]string "error message"
+// @Failure	409 {object} service.DependentExportsError "dependent exports"
 // @Failure	500 {object} map[string]string "error message"
 // @Router /databases/{id} [delete]
 func deleteExportDatabase(serv *service.Serving) (string, string, gin.HandlerFunc) {
 	return http.MethodDelete, "/databases/:id", func(c *gin.Context) {
-
-		errs := serv.DeleteExportDatabase(c.Param("id"), c.GetString(UserIdKey))
+		cascade := c.Query("cascade") == "true"
+		moveTo := c.Query("move_to")
+		if cascade && moveTo != "" {
+			c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": {"move_to": {"can not be combined with cascade"}}})
+			return
+		}
+		errs := serv.DeleteExportDatabase(c.Param("id"), c.GetString(UserIdKey), c.GetHeader("Authorization"), cascade, moveTo)
 		if len(errs) > 0 {
 			for _, err := range errs {
 				if gorm.IsRecordNotFoundError(err) {
 					c.Status(http.StatusNotFound)
 					return
 				}
+				var dependents *service.DependentExportsError
+				if errors.As(err, &dependents) {
+					c.JSON(http.StatusConflict, dependents)
+					return
+				}
+				if errors.Is(err, service.ErrInvalidExport) {
+					c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": {"move_to": {err.Error()}}})
+					return
+				}
 			}
 			util.Logger.Error("could not delete export database", "error", errs)
 			_ = c.Error(errors.New(MessageSomethingWrong))
@@ -464,6 +1047,125 @@ func deleteExportDatabase(serv *service.Serving) (string, string, gin.HandlerFun
 	}
 }
 
+// postExportDatabaseCheck godoc
+// @Summary Check database
+// @Description Connect to the target of an export database and verify its filter topic exists.
+// @Tags Export Database
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "database id"
+// @Success	200 {object} lib.ExportDatabaseCheck "check report"
+// @Failure	404 {object} map[string]string "error message"
+// @Failure	500 {object} map[string]string "error message"
+// @Router /databases/{id}/check [post]
+func postExportDatabaseCheck(serv *service.Serving) (string, string, gin.HandlerFunc) {
+	return http.MethodPost, "/databases/:id/check", func(c *gin.Context) {
+		check, errs := serv.CheckExportDatabase(c.Param("id"), c.GetString(UserIdKey), c.GetHeader("Authorization"))
+		if len(errs) > 0 {
+			for _, err := range errs {
+				if gorm.IsRecordNotFoundError(err) {
+					c.Status(http.StatusNotFound)
+					return
+				}
+			}
+			util.Logger.Error("could not check export database", "error", errs)
+			_ = c.Error(errors.New(MessageSomethingWrong))
+			return
+		}
+		c.JSON(http.StatusOK, check)
+	}
+}
+
+func handleInstanceStateError(c *gin.Context, msg string, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidState) {
+		c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	util.Logger.Error(msg, "error", err)
+	_ = c.Error(errors.New(MessageSomethingWrong))
+}
+
+// bindBundle parses a json or yaml bundle and validates its entries. Errors are written to the response.
+func bindBundle(c *gin.Context) (bundle lib.Bundle, ok bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		util.Logger.Error(MessageParseError, "error", err)
+		_ = c.Error(errors.New(MessageSomethingWrong))
+		return
+	}
+	//yaml is a superset of json
+	if err = yaml.Unmarshal(body, &bundle); err != nil {
+		util.Logger.Error(MessageParseError, "error", err)
+		_ = c.Error(errors.New(MessageSomethingWrong))
+		return
+	}
+	valErrs := map[string][]string{}
+	for i, database := range bundle.ExportDatabases {
+		if validated, errs := ValidateInputs(database); !validated {
+			for field, e := range errs {
+				valErrs["ExportDatabases["+strconv.Itoa(i)+"]."+field] = e
+			}
+		}
+	}
+	for i, export := range bundle.Exports {
+		if validated, errs := ValidateInputs(export); !validated {
+			for field, e := range errs {
+				valErrs["Exports["+strconv.Itoa(i)+"]."+field] = e
+			}
+		}
+	}
+	if len(valErrs) > 0 {
+		c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": valErrs})
+		return
+	}
+	return bundle, true
+}
+
+func bulkStatus(results []lib.BulkResult, success int) int {
+	for _, result := range results {
+		if result.Status == lib.BulkStatusInvalid || result.Status == lib.BulkStatusFailed {
+			return http.StatusMultiStatus
+		}
+	}
+	return success
+}
+
+func handleJobSubmit(c *gin.Context, msg string, job lib.Job, err error) {
+	if err != nil {
+		if errors.Is(err, service.ErrJobsDisabled) {
+			c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		handleInstanceStateError(c, msg, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func unsupportedDatabaseType(c *gin.Context, errs []error) bool {
+	for _, err := range errs {
+		if errors.Is(err, service.ErrUnsupportedDatabaseType) {
+			c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": {"Type": {err.Error()}}})
+			return true
+		}
+	}
+	return false
+}
+
+func credentialsKeyMissing(c *gin.Context, errs []error) bool {
+	for _, err := range errs {
+		if errors.Is(err, service.ErrCredentialsKeyMissing) {
+			c.JSON(http.StatusBadRequest, map[string]map[string][]string{"validationErrors": {"Credentials": {err.Error()}}})
+			return true
+		}
+	}
+	return false
+}
+
 func getHealthCheckH(_ *service.Serving) (string, string, gin.HandlerFunc) {
 	return http.MethodGet, HealthCheckPath, func(c *gin.Context) {
 		c.Status(http.StatusOK)
@@ -34,14 +34,31 @@ var routesAdmin = gin_mw.Routes[*service.Serving]{
 
 var routesAuth = gin_mw.Routes[*service.Serving]{
 	postNewServingInstance,
+	getServingInstanceBundle,
+	postServingInstanceBundle,
+	postApply,
+	postServingInstancePreview,
+	postServingInstanceMappingTest,
 	putNewServingInstance,
+	postServingInstancePause,
+	postServingInstanceResume,
+	postServingInstanceRestore,
+	postServingInstanceReplay,
+	postServingInstanceMove,
+	postServingInstanceClone,
+	getServingInstanceRevisions,
+	postServingInstanceRevisionRestore,
 	getServingInstance,
 	getServingInstances,
 	deleteServingInstance,
+	postNewServingInstances,
+	putNewServingInstances,
 	deleteServingInstances,
+	getJob,
 	getExportDatabases,
 	getExportDatabase,
 	postExportDatabase,
 	putExportDatabase,
 	deleteExportDatabase,
+	postExportDatabaseCheck,
 }
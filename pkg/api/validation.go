@@ -19,6 +19,7 @@ package api
 import (
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
 	"github.com/go-playground/validator/v10"
@@ -36,6 +37,8 @@ func ValidateInputs(dataSet interface{}) (bool, map[string][]string) {
 		}
 		return name
 	})
+	_ = validate.RegisterValidation("offset", validateOffset)
+	_ = validate.RegisterValidation("future", validateFuture)
 	err := validate.Struct(dataSet)
 
 	if err != nil {
@@ -56,6 +59,12 @@ func ValidateInputs(dataSet interface{}) (bool, map[string][]string) {
 			case "min":
 				errors[err.Field()] = append(errors[err.Field()], "The field '"+err.Field()+"' must be at least have "+err.Param()+" chars")
 				break
+			case "offset":
+				errors[err.Field()] = append(errors[err.Field()], "The field '"+err.Field()+"' must be 'earliest', 'latest', a RFC3339 timestamp or a relative duration like '7d'")
+				break
+			case "future":
+				errors[err.Field()] = append(errors[err.Field()], "The field '"+err.Field()+"' must be in the future")
+				break
 			}
 		}
 		return false, errors
@@ -63,3 +72,13 @@ func ValidateInputs(dataSet interface{}) (bool, map[string][]string) {
 
 	return true, nil
 }
+
+func validateOffset(fl validator.FieldLevel) bool {
+	_, err := util.ResolveOffset(fl.Field().String(), time.Now())
+	return err == nil
+}
+
+func validateFuture(fl validator.FieldLevel) bool {
+	t, ok := fl.Field().Interface().(time.Time)
+	return ok && t.After(time.Now())
+}
@@ -17,7 +17,6 @@
 package ew_api
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,6 +27,7 @@ import (
 	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/service"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/segmentio/kafka-go"
 )
@@ -43,13 +43,37 @@ func NewExportWorker(cfg config.KafkaConfig, kafkaProducer *kafka.Writer, kafkaC
 	return &ExportWorker{kafkaProducer, kafkaConn, kafkaControllerConn, &cfg}
 }
 
-func (ew *ExportWorker) CreateInstance(instance *lib.Instance, dataFields string, tagFields string) (serviceId string, err error) {
+// CreateInstance records a put message for the filter of an instance in the outbox, it is published by the outbox relay
+// once tx is committed.
+func (ew *ExportWorker) CreateInstance(tx *gorm.DB, instance *lib.Instance, dataFields string, tagFields string) (serviceId string, err error) {
 	serviceId = ""
+	message, err := genPutMessage(instance, dataFields, tagFields)
+	if err != nil {
+		return
+	}
+	err = enqueue(tx, &message, instance.ID.String(), instance.ExportDatabase.EwFilterTopic)
+	return
+}
+
+// PreviewInstance returns the filter message CreateInstance would publish without publishing it.
+func (ew *ExportWorker) PreviewInstance(instance *lib.Instance, dataFields string, tagFields string) (preview interface{}, err error) {
+	message, err := genPutMessage(instance, dataFields, tagFields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", service.ErrInvalidExport, err.Error())
+	}
+	return message, nil
+}
+
+func genPutMessage(instance *lib.Instance, dataFields string, tagFields string) (message Message, err error) {
 	filter := Filter{
 		Source:      instance.Topic,
 		Identifiers: []Identifier{},
 		Mappings:    map[string]string{},
 		ID:          instance.ID.String(),
+		Offset:      instance.Offset,
+	}
+	if instance.ApplicationId != uuid.Nil {
+		filter.ApplicationId = instance.ApplicationId.String()
 	}
 	var dataFieldsMap map[string]string
 	err = genFieldsMap(&dataFieldsMap, &dataFields)
@@ -61,7 +85,7 @@ func (ew *ExportWorker) CreateInstance(instance *lib.Instance, dataFields string
 	if err != nil {
 		return
 	}
-	genIdentifiers(&filter.Identifiers, instance.FilterType, instance.Filter, instance.Topic)
+	err = genIdentifiers(&filter.Identifiers, instance.FilterType, instance.Filter, instance.Topic)
 	if err != nil {
 		return
 	}
@@ -80,6 +104,20 @@ func (ew *ExportWorker) CreateInstance(instance *lib.Instance, dataFields string
 			return
 		}
 		filter.Args = influxDBExportArgs
+	case InfluxDB2:
+		if len(dataFieldsMap) > 0 {
+			addMappings(filter.Mappings, dataFieldsMap, MappingData)
+		}
+		if len(tagFieldsMap) > 0 {
+			addMappings(filter.Mappings, tagFieldsMap, MappingExtra)
+		}
+		addInfluxDBTimeMapping(filter.Mappings, instance.TimePath)
+		influxDB2ExportArgs := InfluxDB2ExportArgs{}
+		err = genInflux2ExportArgs(&influxDB2ExportArgs, instance.InfluxBucket(), instance.ExportDatabase.Org, instance.TimePath, instance.TimestampFormat, instance.TimePrecision, dataFieldsMap, tagFieldsMap)
+		if err != nil {
+			return
+		}
+		filter.Args = influxDB2ExportArgs
 	case TimescaleDB:
 		fieldsMap := map[string]string{}
 		if len(dataFieldsMap) > 0 {
@@ -104,20 +142,40 @@ func (ew *ExportWorker) CreateInstance(instance *lib.Instance, dataFields string
 			return
 		}
 		filter.Args = timescaleDBExportArgs
+	case Postgres:
+		fieldsMap := map[string]string{}
+		if len(dataFieldsMap) > 0 {
+			addMappings(filter.Mappings, dataFieldsMap, MappingData)
+			for k, v := range dataFieldsMap {
+				fieldsMap[k] = v
+			}
+		}
+		if len(tagFieldsMap) > 0 {
+			addMappings(filter.Mappings, tagFieldsMap, MappingData)
+			for k, v := range tagFieldsMap {
+				fieldsMap[k] = v
+			}
+		}
+		addPostgresTimeMapping(filter.Mappings, instance.TimePath)
+		postgresExportArgs := PostgresExportArgs{}
+		err = genPostgresExportArgs(&postgresExportArgs, instance.ID.String(), instance.Database, instance.TimePath, instance.TimestampFormat, instance.TimestampUnique, fieldsMap, genPostgresPrimaryKey(instance.Values, instance.TimePath, instance.TimestampUnique))
+		if err != nil {
+			return
+		}
+		filter.Args = postgresExportArgs
 	default:
 		err = errors.New("unknown or missing database type")
 		return
 	}
-	message := Message{
+	message = Message{
 		Method:    MethodPut,
 		Payload:   filter,
 		Timestamp: time.Now().UTC().Unix(),
 	}
-	err = ew.publish(&message, instance.ID.String(), instance.ExportDatabase.EwFilterTopic)
 	return
 }
 
-func (ew *ExportWorker) DeleteInstance(instance *lib.Instance) (err error) {
+func (ew *ExportWorker) DeleteInstance(tx *gorm.DB, instance *lib.Instance) (err error) {
 	message := Message{
 		Method: MethodDelete,
 		Payload: Filter{
@@ -125,18 +183,30 @@ func (ew *ExportWorker) DeleteInstance(instance *lib.Instance) (err error) {
 		},
 		Timestamp: time.Now().UTC().Unix(),
 	}
-	err = ew.publish(&message, instance.ID.String(), instance.ExportDatabase.EwFilterTopic)
+	err = enqueue(tx, &message, instance.ID.String(), instance.ExportDatabase.EwFilterTopic)
 	return
 }
 
+func (ew *ExportWorker) ValidateExportDatabaseType(dbType string) error {
+	if !stringInSlice(&databaseTypes, dbType) {
+		return fmt.Errorf("%w: '%s'", service.ErrUnsupportedDatabaseType, dbType)
+	}
+	return nil
+}
+
+func (ew *ExportWorker) FilterTopicExists(topic string) (bool, error) {
+	partitions, err := ew.kafkaConn.ReadPartitions()
+	if err != nil {
+		return false, err
+	}
+	return checkTopic(&partitions, topic), nil
+}
+
 func (ew *ExportWorker) CreateFilterTopic(topic string, checkExists bool) (err error) {
 	if checkExists {
-		var partitions []kafka.Partition
-		partitions, err = ew.kafkaConn.ReadPartitions()
-		if err != nil {
-			return
-		}
-		if checkTopic(&partitions, topic) {
+		var exists bool
+		exists, err = ew.FilterTopicExists(topic)
+		if err != nil || exists {
 			return
 		}
 	}
@@ -220,16 +290,18 @@ func (ew *ExportWorker) InitFilterTopics(serving *service.Serving) (err error) {
 	return
 }
 
-func (ew *ExportWorker) publish(message *Message, key string, topic string) (err error) {
+func enqueue(tx *gorm.DB, message *Message, key string, topic string) (err error) {
 	var jsonByte []byte
 	jsonByte, err = json.Marshal(message)
 	if err != nil {
 		return
 	}
-	err = ew.kafkaProducer.WriteMessages(context.Background(), kafka.Message{
-		Topic: topic,
-		Key:   []byte(key),
-		Value: jsonByte,
+	err = db.AddOutboxMessage(tx, &db.OutboxMessage{
+		Topic:      topic,
+		Key:        key,
+		Payload:    string(jsonByte),
+		Method:     message.Method,
+		InstanceID: key,
 	})
 	return
 }
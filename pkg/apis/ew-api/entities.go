@@ -28,11 +28,13 @@ type Message struct {
 }
 
 type Filter struct {
-	Source      string            `json:"source,omitempty"`
-	Identifiers []Identifier      `json:"identifiers,omitempty"`
-	Mappings    map[string]string `json:"mappings,omitempty"`
-	ID          string            `json:"id"`
-	Args        interface{}       `json:"args,omitempty"`
+	Source        string            `json:"source,omitempty"`
+	Identifiers   []Identifier      `json:"identifiers,omitempty"`
+	Mappings      map[string]string `json:"mappings,omitempty"`
+	ID            string            `json:"id"`
+	ApplicationId string            `json:"application_id,omitempty"`
+	Offset        string            `json:"offset,omitempty"`
+	Args          interface{}       `json:"args,omitempty"`
 }
 
 type Identifier struct {
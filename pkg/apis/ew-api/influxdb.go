@@ -1,6 +1,7 @@
 package ew_api
 
 import (
+	"errors"
 	"strings"
 )
 
@@ -56,3 +57,33 @@ func genInfluxExportArgs(args *InfluxDBExportArgs, dbName string, timePath strin
 	}
 	return
 }
+
+type InfluxDB2ExportArgs struct {
+	Bucket        string            `json:"bucket"`
+	Org           string            `json:"org"`
+	TypeCasts     map[string]string `json:"type_casts,omitempty"`
+	TimeKey       string            `json:"time_key,omitempty"`
+	TimeFormat    string            `json:"time_format,omitempty"`
+	TimePrecision string            `json:"time_precision,omitempty"`
+}
+
+func genInflux2ExportArgs(args *InfluxDB2ExportArgs, bucket string, org string, timePath string, timeFormat string, timePrecision *string, dataFieldsMap map[string]string, tagFieldsMap map[string]string) (err error) {
+	if org == "" {
+		err = errors.New("export-database is missing an organization")
+		return
+	}
+	v1Args := InfluxDBExportArgs{}
+	err = genInfluxExportArgs(&v1Args, bucket, timePath, timePrecision, dataFieldsMap, tagFieldsMap)
+	if err != nil {
+		return
+	}
+	args.Bucket = bucket
+	args.Org = org
+	args.TypeCasts = v1Args.TypeCasts
+	args.TimeKey = v1Args.TimeKey
+	if timePath != "" {
+		args.TimeFormat = timeFormat
+	}
+	args.TimePrecision = v1Args.TimePrecision
+	return
+}
@@ -0,0 +1,215 @@
+package ew_api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/service"
+)
+
+var strftimeLayouts = []string{
+	"%Y", "2006",
+	"%y", "06",
+	"%m", "01",
+	"%d", "02",
+	"%H", "15",
+	"%I", "03",
+	"%p", "PM",
+	"%M", "04",
+	"%S", "05",
+	"%f", "000000",
+	"%z", "-0700",
+	"%Z", "MST",
+	"%b", "Jan",
+	"%B", "January",
+	"%a", "Mon",
+	"%A", "Monday",
+	"%%", "%",
+}
+
+// TestMapping applies the mapping of an export to a sample message and returns what would be written.
+func (ew *ExportWorker) TestMapping(instance *lib.Instance, dataFields string, tagFields string, sample interface{}) (result lib.MappingTestResult, err error) {
+	message, err := genPutMessage(instance, dataFields, tagFields)
+	if err != nil {
+		return result, fmt.Errorf("%w: %s", service.ErrInvalidExport, err.Error())
+	}
+	result.Fields = map[string]interface{}{}
+	switch args := message.Payload.Args.(type) {
+	case InfluxDBExportArgs, InfluxDB2ExportArgs:
+		result.Target = instance.Measurement
+		result.Tags = map[string]interface{}{}
+	case TimescaleDBExportArgs:
+		result.Target = args.TableName
+	case PostgresExportArgs:
+		result.Target = args.TableName
+	}
+	for _, value := range instance.Values {
+		raw, ok := resolvePath(sample, value.Path)
+		if !ok {
+			result.Errors = append(result.Errors, "path '"+value.Path+"' of '"+value.Name+"' not found")
+			continue
+		}
+		casted, e := castValue(raw, value.Type)
+		if e != nil {
+			result.Errors = append(result.Errors, "casting '"+value.Name+"' failed - "+e.Error())
+			continue
+		}
+		if value.Tag && result.Tags != nil {
+			result.Tags[value.Name] = fmt.Sprint(casted)
+		} else {
+			result.Fields[value.Name] = casted
+		}
+	}
+	if instance.TimePath == "" {
+		now := time.Now().UTC()
+		result.Time = &now
+		return result, nil
+	}
+	raw, ok := resolvePath(sample, instance.TimePath)
+	if !ok {
+		result.Errors = append(result.Errors, "time path '"+instance.TimePath+"' not found")
+		return result, nil
+	}
+	precision := ""
+	if instance.TimePrecision != nil {
+		precision = *instance.TimePrecision
+	}
+	timestamp, e := parseTimestamp(raw, instance.TimestampFormat, precision)
+	if e != nil {
+		result.Errors = append(result.Errors, "parsing timestamp failed - "+e.Error())
+		return result, nil
+	}
+	result.Time = &timestamp
+	return result, nil
+}
+
+func resolvePath(sample interface{}, path string) (value interface{}, ok bool) {
+	value = sample
+	for _, key := range strings.Split(path, ".") {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			value, ok = current[key]
+			if !ok {
+				return nil, false
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			value = current[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func castValue(raw interface{}, valueType string) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	switch valueType {
+	case "string":
+		switch v := raw.(type) {
+		case string:
+			return v, nil
+		case float64, bool:
+			return fmt.Sprint(v), nil
+		}
+	case "string_json":
+		b, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "float":
+		switch v := raw.(type) {
+		case float64:
+			return v, nil
+		case string:
+			return strconv.ParseFloat(v, 64)
+		}
+	case "int":
+		switch v := raw.(type) {
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("%v is not an integer", v)
+			}
+			return int64(v), nil
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		}
+	case "bool":
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	default:
+		return nil, errors.New("unknown type '" + valueType + "'")
+	}
+	return nil, fmt.Errorf("can not convert %T to %s", raw, valueType)
+}
+
+func parseTimestamp(raw interface{}, format string, precision string) (timestamp time.Time, err error) {
+	switch v := raw.(type) {
+	case float64:
+		return unixTimestamp(int64(v), format, precision)
+	case string:
+		switch format {
+		case "", time.RFC3339, "rfc3339":
+			if n, e := strconv.ParseInt(v, 10, 64); e == nil {
+				return unixTimestamp(n, format, precision)
+			}
+			timestamp, err = time.Parse(time.RFC3339Nano, v)
+		case "unix", "unix_ms", "unix_us", "unix_ns":
+			var n int64
+			n, err = strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return
+			}
+			return unixTimestamp(n, format, precision)
+		default:
+			layout := format
+			if strings.Contains(format, "%") {
+				layout = strings.NewReplacer(strftimeLayouts...).Replace(format)
+			}
+			timestamp, err = time.Parse(layout, v)
+		}
+		return timestamp.UTC(), err
+	}
+	return timestamp, fmt.Errorf("unsupported timestamp type %T", raw)
+}
+
+func unixTimestamp(n int64, format string, precision string) (time.Time, error) {
+	unit := precision
+	switch format {
+	case "unix":
+		unit = "s"
+	case "unix_ms":
+		unit = "ms"
+	case "unix_us":
+		unit = "us"
+	case "unix_ns":
+		unit = "ns"
+	}
+	switch unit {
+	case "s", "":
+		return time.Unix(n, 0).UTC(), nil
+	case "ms":
+		return time.UnixMilli(n).UTC(), nil
+	case "us", "u":
+		return time.UnixMicro(n).UTC(), nil
+	case "ns", "n":
+		return time.Unix(0, n).UTC(), nil
+	}
+	return time.Time{}, errors.New("unknown time precision '" + unit + "'")
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ew_api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/google/uuid"
+)
+
+func TestMapping(t *testing.T) {
+	instance := lib.Instance{
+		ID:             uuid.New(),
+		Measurement:    "measurement",
+		Topic:          "topic",
+		Filter:         "device",
+		FilterType:     "deviceId",
+		TimePath:       "time",
+		Database:       "user",
+		ExportDatabase: lib.ExportDatabase{Type: InfluxDB},
+		Values: []lib.Value{
+			{Name: "temperature", Type: "float", Path: "value.t"},
+			{Name: "location", Type: "string", Path: "value.loc", Tag: true},
+		},
+	}
+	var sample interface{}
+	err := json.Unmarshal([]byte(`{"time":"2025-03-04T05:06:07Z","value":{"t":"21.5","loc":"kitchen"}}`), &sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ew := &ExportWorker{}
+	result, err := ew.TestMapping(&instance, `{"temperature:float":"value.t"}`, `{"location:string":"value.loc"}`, sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Target != "measurement" || len(result.Errors) > 0 {
+		t.Error("unexpected result", result.Target, result.Errors)
+	}
+	if !reflect.DeepEqual(result.Fields, map[string]interface{}{"temperature": 21.5}) {
+		t.Error("unexpected fields", result.Fields)
+	}
+	if !reflect.DeepEqual(result.Tags, map[string]interface{}{"location": "kitchen"}) {
+		t.Error("unexpected tags", result.Tags)
+	}
+	if result.Time == nil || !result.Time.Equal(time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Error("unexpected time", result.Time)
+	}
+
+	err = json.Unmarshal([]byte(`{"time":"yesterday","value":{"t":"warm"}}`), &sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = ew.TestMapping(&instance, `{"temperature:float":"value.t"}`, `{"location:string":"value.loc"}`, sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 3 || len(result.Fields) > 0 || result.Time != nil {
+		t.Error("expected a cast, a path and a timestamp error", result.Errors)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	var sample interface{}
+	err := json.Unmarshal([]byte(`{"value":{"a":1.5,"list":[{"b":"x"},{"b":"y"}],"null":null}}`), &sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := resolvePath(sample, "value.a"); !ok || value != 1.5 {
+		t.Error("unexpected value", value, ok)
+	}
+	if value, ok := resolvePath(sample, "value.list.1.b"); !ok || value != "y" {
+		t.Error("unexpected list element", value, ok)
+	}
+	if value, ok := resolvePath(sample, "value.null"); !ok || value != nil {
+		t.Error("a null value must be found", value, ok)
+	}
+	for _, path := range []string{"value.missing", "value.list.2.b", "value.list.-1.b", "value.list.x", "value.a.b"} {
+		if value, ok := resolvePath(sample, path); ok {
+			t.Error("unexpected value for", path, value)
+		}
+	}
+}
+
+func TestCastValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       interface{}
+		valueType string
+		expected  interface{}
+		err       bool
+	}{
+		{"nil", nil, "float", nil, false},
+		{"string", "a", "string", "a", false},
+		{"number to string", 1.5, "string", "1.5", false},
+		{"bool to string", true, "string", "true", false},
+		{"object to string", map[string]interface{}{"a": 1.0}, "string", nil, true},
+		{"object to json", map[string]interface{}{"a": 1.0}, "string_json", `{"a":1}`, false},
+		{"float", 1.5, "float", 1.5, false},
+		{"string to float", "1.5", "float", 1.5, false},
+		{"invalid string to float", "a", "float", nil, true},
+		{"bool to float", true, "float", nil, true},
+		{"int", 2.0, "int", int64(2), false},
+		{"fraction to int", 2.5, "int", nil, true},
+		{"string to int", "2", "int", int64(2), false},
+		{"bool", false, "bool", false, false},
+		{"string to bool", "true", "bool", true, false},
+		{"number to bool", 1.0, "bool", nil, true},
+		{"unknown type", 1.0, "list", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := castValue(test.raw, test.valueType)
+			if (err != nil) != test.err {
+				t.Errorf("unexpected error %v", err)
+				return
+			}
+			if !test.err && !reflect.DeepEqual(value, test.expected) {
+				t.Errorf("expected %#v, got %#v", test.expected, value)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		name      string
+		raw       interface{}
+		format    string
+		precision string
+		expected  time.Time
+		err       bool
+	}{
+		{"rfc3339", "2025-03-04T05:06:07Z", "", "", expected, false},
+		{"rfc3339 with offset", "2025-03-04T07:06:07+02:00", "rfc3339", "", expected, false},
+		{"rfc3339 nano", "2025-03-04T05:06:07.5Z", time.RFC3339, "", expected.Add(500 * time.Millisecond), false},
+		{"number", float64(expected.Unix()), "", "", expected, false},
+		{"number with precision", float64(expected.UnixMilli()), "", "ms", expected, false},
+		{"numeric string", "1741064767", "", "", expected, false},
+		{"unix", "1741064767", "unix", "ms", expected, false},
+		{"unix_ms", float64(expected.UnixMilli()), "unix_ms", "", expected, false},
+		{"unix_us", "1741064767000000", "unix_us", "", expected, false},
+		{"unix_ns", "1741064767000000000", "unix_ns", "", expected, false},
+		{"invalid unix", "a", "unix", "", time.Time{}, true},
+		{"go layout", "2025-03-04 05:06:07", "2006-01-02 15:04:05", "", expected, false},
+		{"strftime", "04.03.2025 05:06:07", "%d.%m.%Y %H:%M:%S", "", expected, false},
+		{"strftime mismatch", "2025-03-04", "%d.%m.%Y", "", time.Time{}, true},
+		{"unknown precision", 1.0, "", "h", time.Time{}, true},
+		{"unsupported type", true, "", "", time.Time{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timestamp, err := parseTimestamp(test.raw, test.format, test.precision)
+			if (err != nil) != test.err {
+				t.Errorf("unexpected error %v", err)
+				return
+			}
+			if !test.err && !timestamp.Equal(test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, timestamp)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ew_api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	"github.com/segmentio/kafka-go"
+)
+
+// StartOutboxRelay publishes pending outbox messages to their filter topics in insertion order until ctx is done.
+// Messages stay in the outbox until kafka acknowledged them, which results in at-least-once delivery across restarts.
+// Failed messages are retried with backoff and parked after the maximum attempts, they do not delay other instances.
+func (ew *ExportWorker) StartOutboxRelay(ctx context.Context, wg *sync.WaitGroup, cfg config.OutboxConfig) (err error) {
+	interval, err := time.ParseDuration(cfg.Interval)
+	if err != nil {
+		return
+	}
+	retention, err := time.ParseDuration(cfg.Retention)
+	if err != nil {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		lastCleanup := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				util.Logger.Info("stopped outbox relay")
+				return
+			case <-ticker.C:
+				ew.relayOutbox(ctx, cfg.BatchSize, cfg.MaxAttempts, interval)
+				if time.Since(lastCleanup) > time.Minute {
+					if err := db.DeleteSentOutboxMessages(time.Now().Add(-retention)); err != nil {
+						util.Logger.Error("could not remove sent outbox messages", "error", err)
+					}
+					lastCleanup = time.Now()
+				}
+			}
+		}
+	}()
+	util.Logger.Info("started outbox relay")
+	return
+}
+
+// maxOutboxBackoff limits the delay between publish attempts of a failed message.
+const maxOutboxBackoff = 10 * time.Minute
+
+type outboxWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+type failedOutboxMessage struct {
+	message db.OutboxMessage
+	err     error
+}
+
+func (ew *ExportWorker) relayOutbox(ctx context.Context, batchSize int, maxAttempts int, interval time.Duration) {
+	for ctx.Err() == nil {
+		messages, err := db.GetPendingOutboxMessages(batchSize)
+		if err != nil {
+			util.Logger.Error("could not read outbox", "error", err)
+			return
+		}
+		if len(messages) == 0 {
+			return
+		}
+		sent, failed := publishOutboxMessages(ctx, ew.kafkaProducer, messages)
+		for _, f := range failed {
+			util.Logger.Error("could not publish outbox message", "error", f.err, "id", f.message.ID, "topic", f.message.Topic, "attempts", f.message.Attempts+1)
+			err = db.SetOutboxMessageError(f.message, f.err, maxAttempts, time.Now().Add(outboxBackoff(interval, f.message.Attempts)))
+			if err != nil {
+				util.Logger.Error("could not record outbox error", "error", err)
+				return
+			}
+		}
+		if len(sent) > 0 {
+			err = db.MarkOutboxMessagesSent(sent, MethodPut)
+			if err != nil {
+				util.Logger.Error("could not mark outbox messages as sent", "error", err)
+				return
+			}
+			util.Logger.Debug("published outbox messages", "count", len(sent))
+		}
+		if len(messages) < batchSize {
+			return
+		}
+	}
+}
+
+// publishOutboxMessages publishes messages one by one, so a message that can not be published does not fail
+// the others. Later messages with the key of a failed message are skipped to keep them in order.
+func publishOutboxMessages(ctx context.Context, writer outboxWriter, messages []db.OutboxMessage) (sent []db.OutboxMessage, failed []failedOutboxMessage) {
+	blocked := map[string]bool{}
+	for _, message := range messages {
+		if blocked[message.Key] {
+			continue
+		}
+		err := writer.WriteMessages(ctx, kafka.Message{
+			Topic: message.Topic,
+			Key:   []byte(message.Key),
+			Value: []byte(message.Payload),
+		})
+		if err != nil {
+			blocked[message.Key] = true
+			failed = append(failed, failedOutboxMessage{message: message, err: err})
+			continue
+		}
+		sent = append(sent, message)
+	}
+	return
+}
+
+// outboxBackoff doubles the relay interval with every failed attempt.
+func outboxBackoff(interval time.Duration, attempts int) time.Duration {
+	if attempts > 20 {
+		return maxOutboxBackoff
+	}
+	return min(interval<<attempts, maxOutboxBackoff)
+}
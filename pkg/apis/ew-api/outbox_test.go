@@ -0,0 +1,85 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ew_api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/segmentio/kafka-go"
+)
+
+type testOutboxWriter struct {
+	failingTopics map[string]bool
+	written       []string
+}
+
+func (w *testOutboxWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	for _, msg := range msgs {
+		if w.failingTopics[msg.Topic] {
+			return errors.New("unknown topic " + msg.Topic)
+		}
+		w.written = append(w.written, string(msg.Value))
+	}
+	return nil
+}
+
+func TestPublishOutboxMessagesFailingHead(t *testing.T) {
+	writer := &testOutboxWriter{failingTopics: map[string]bool{"missing": true}}
+	messages := []db.OutboxMessage{
+		{ID: 1, Topic: "missing", Key: "a", Payload: "a1"},
+		{ID: 2, Topic: "filters", Key: "b", Payload: "b1"},
+		{ID: 3, Topic: "filters", Key: "a", Payload: "a2"},
+		{ID: 4, Topic: "filters", Key: "c", Payload: "c1"},
+		{ID: 5, Topic: "filters", Key: "b", Payload: "b2"},
+	}
+	sent, failed := publishOutboxMessages(context.Background(), writer, messages)
+	if len(failed) != 1 || failed[0].message.ID != 1 || failed[0].err == nil {
+		t.Error("expected only the head to fail", failed)
+	}
+	var sentIds []uint64
+	for _, message := range sent {
+		sentIds = append(sentIds, message.ID)
+	}
+	if len(sentIds) != 3 || sentIds[0] != 2 || sentIds[1] != 4 || sentIds[2] != 5 {
+		t.Error("expected messages of other keys to be sent in order", sentIds)
+	}
+	if len(writer.written) != 3 {
+		t.Error("unexpected written messages", writer.written)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{10, maxOutboxBackoff},
+		{100, maxOutboxBackoff},
+	}
+	for _, test := range tests {
+		if backoff := outboxBackoff(time.Second, test.attempts); backoff != test.expected {
+			t.Errorf("attempts %d: expected %v, got %v", test.attempts, test.expected, backoff)
+		}
+	}
+}
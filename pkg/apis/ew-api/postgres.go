@@ -0,0 +1,116 @@
+package ew_api
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+)
+
+const (
+	PostgresTimeColumn = "time"
+)
+
+var postgresTypeMap = map[string][2]string{
+	"string":      {"text", "NULL"},
+	"float":       {"double precision", "NULL"},
+	"int":         {"bigint", "NULL"},
+	"bool":        {"boolean", "NULL"},
+	"string_json": {"jsonb", "NULL"},
+}
+
+type PostgresExportArgs struct {
+	TableName        string      `json:"table_name"`
+	TableColumns     [][3]string `json:"table_columns"`
+	TimeColumn       string      `json:"time_column,omitempty"`
+	TimeFormat       string      `json:"time_format,omitempty"`
+	PrimaryKey       []string    `json:"primary_key,omitempty"`
+	UniqueConstraint []string    `json:"unique_constraint,omitempty"`
+}
+
+func addPostgresTimeMapping(mappings map[string]string, timePath string) {
+	if timePath != "" {
+		mappings[PostgresTimeColumn+MappingData] = timePath
+	}
+}
+
+func addPostgresColumns(columns *[][3]string, fieldsMap map[string]string, timePath string) (err error) {
+	if timePath != "" {
+		*columns = append(*columns, [3]string{PostgresTimeColumn, "TIMESTAMPTZ", "NOT NULL"})
+	}
+	for key := range fieldsMap {
+		dst := strings.Split(key, ":")
+		colType, ok := postgresTypeMap[dst[1]]
+		if !ok {
+			err = errors.New("unsupported type '" + dst[1] + "' for column '" + dst[0] + "'")
+			return
+		}
+		if timePath != "" && dst[0] == PostgresTimeColumn {
+			err = errors.New("column name '" + PostgresTimeColumn + "' is reserved for timestamps")
+			return
+		}
+		*columns = append(*columns, [3]string{dst[0], colType[0], colType[1]})
+	}
+	return
+}
+
+func genPostgresPrimaryKey(values []lib.Value, timePath string, timeUnique bool) (primaryKey []string) {
+	for _, value := range values {
+		if value.PrimaryKey {
+			primaryKey = append(primaryKey, value.Name)
+		}
+	}
+	if len(primaryKey) > 0 && timePath != "" && timeUnique {
+		primaryKey = append([]string{PostgresTimeColumn}, primaryKey...)
+	}
+	return
+}
+
+func genPostgresExportArgs(args *PostgresExportArgs, exportID string, dbName string, timePath string, timeFormat string, timeUnique bool, fieldsMap map[string]string, primaryKey []string) (err error) {
+	if timePath != "" {
+		if timeFormat == "" {
+			err = errors.New("timestamp format required")
+			return
+		}
+		args.TimeColumn = PostgresTimeColumn
+		args.TimeFormat = timeFormat
+		if timeUnique && len(primaryKey) == 0 {
+			args.UniqueConstraint = []string{PostgresTimeColumn}
+		}
+	} else if timeUnique {
+		err = errors.New("column containing timestamps required for unique timestamps")
+		return
+	}
+	args.TableName, err = util.TableName(exportID, dbName)
+	if err != nil {
+		return
+	}
+	var columns [][3]string
+	err = addPostgresColumns(&columns, fieldsMap, timePath)
+	if err != nil {
+		return
+	}
+	if len(columns) == 0 {
+		err = errors.New("at least one column required")
+		return
+	}
+	for _, key := range primaryKey {
+		if !hasColumn(columns, key) {
+			err = errors.New("primary key column '" + key + "' does not exist")
+			return
+		}
+	}
+	args.TableColumns = columns
+	args.PrimaryKey = primaryKey
+	return
+}
+
+func hasColumn(columns [][3]string, name string) bool {
+	for _, column := range columns {
+		if column[0] == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ew_api
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+)
+
+const (
+	testExportId = "1c2d3e4f-0000-4000-8000-000000000001"
+	testUserId   = "2c2d3e4f-0000-4000-8000-000000000002"
+)
+
+func TestGenPostgresExportArgs(t *testing.T) {
+	var args PostgresExportArgs
+	fields := map[string]string{"a:float": "value.a", "b:string_json": "value.b", "c:int": "value.c"}
+	err := genPostgresExportArgs(&args, testExportId, testUserId, "value.time", "unix", false, fields, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.TableName != "userid:LC0-TwAAQACAAAAAAAAAAg_export:HC0-TwAAQACAAAAAAAAAAQ" {
+		t.Error("unexpected table name", args.TableName)
+	}
+	sort.Slice(args.TableColumns, func(i, j int) bool { return args.TableColumns[i][0] < args.TableColumns[j][0] })
+	columns := [][3]string{
+		{"a", "double precision", "NULL"},
+		{"b", "jsonb", "NULL"},
+		{"c", "bigint", "NULL"},
+		{"time", "TIMESTAMPTZ", "NOT NULL"},
+	}
+	if !reflect.DeepEqual(args.TableColumns, columns) {
+		t.Error("unexpected columns", args.TableColumns)
+	}
+	if args.TimeColumn != PostgresTimeColumn || args.TimeFormat != "unix" {
+		t.Error("unexpected time column", args.TimeColumn, args.TimeFormat)
+	}
+	if args.UniqueConstraint != nil || args.PrimaryKey != nil {
+		t.Error("unexpected constraints", args.UniqueConstraint, args.PrimaryKey)
+	}
+}
+
+func TestGenPostgresExportArgsUniqueTime(t *testing.T) {
+	fields := map[string]string{"a:float": "value.a"}
+	var args PostgresExportArgs
+	if err := genPostgresExportArgs(&args, testExportId, testUserId, "value.time", "unix", true, fields, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args.UniqueConstraint, []string{PostgresTimeColumn}) {
+		t.Error("expected unique time constraint, got", args.UniqueConstraint)
+	}
+	// a primary key containing the time column already makes timestamps unique
+	args = PostgresExportArgs{}
+	if err := genPostgresExportArgs(&args, testExportId, testUserId, "value.time", "unix", true, fields, []string{"time", "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if args.UniqueConstraint != nil || !reflect.DeepEqual(args.PrimaryKey, []string{"time", "a"}) {
+		t.Error("unexpected constraints", args.UniqueConstraint, args.PrimaryKey)
+	}
+}
+
+func TestGenPostgresExportArgsWithoutTime(t *testing.T) {
+	var args PostgresExportArgs
+	if err := genPostgresExportArgs(&args, testExportId, testUserId, "", "", false, map[string]string{"a:string": "value.a"}, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args.TableColumns, [][3]string{{"a", "text", "NULL"}}) || args.TimeColumn != "" {
+		t.Error("unexpected columns", args.TableColumns, args.TimeColumn)
+	}
+}
+
+func TestGenPostgresExportArgsInvalid(t *testing.T) {
+	fields := map[string]string{"a:float": "value.a"}
+	tests := map[string]func(args *PostgresExportArgs) error{
+		"missing time format": func(args *PostgresExportArgs) error {
+			return genPostgresExportArgs(args, testExportId, testUserId, "value.time", "", false, fields, nil)
+		},
+		"unique time without time path": func(args *PostgresExportArgs) error {
+			return genPostgresExportArgs(args, testExportId, testUserId, "", "", true, fields, nil)
+		},
+		"no columns": func(args *PostgresExportArgs) error {
+			return genPostgresExportArgs(args, testExportId, testUserId, "", "", false, map[string]string{}, nil)
+		},
+		"unsupported type": func(args *PostgresExportArgs) error {
+			return genPostgresExportArgs(args, testExportId, testUserId, "", "", false, map[string]string{"a:list": "value.a"}, nil)
+		},
+		"reserved time column": func(args *PostgresExportArgs) error {
+			return genPostgresExportArgs(args, testExportId, testUserId, "value.time", "unix", false, map[string]string{"time:int": "value.t"}, nil)
+		},
+		"unknown primary key column": func(args *PostgresExportArgs) error {
+			return genPostgresExportArgs(args, testExportId, testUserId, "", "", false, fields, []string{"b"})
+		},
+	}
+	for name, gen := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := gen(&PostgresExportArgs{}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestGenPostgresPrimaryKey(t *testing.T) {
+	values := []lib.Value{{Name: "a", PrimaryKey: true}, {Name: "b"}, {Name: "c", PrimaryKey: true}}
+	if key := genPostgresPrimaryKey(values, "value.time", true); !reflect.DeepEqual(key, []string{"time", "a", "c"}) {
+		t.Error("unexpected primary key", key)
+	}
+	if key := genPostgresPrimaryKey(values, "value.time", false); !reflect.DeepEqual(key, []string{"a", "c"}) {
+		t.Error("unexpected primary key", key)
+	}
+	if key := genPostgresPrimaryKey(values[1:2], "value.time", true); key != nil {
+		t.Error("unexpected primary key", key)
+	}
+}
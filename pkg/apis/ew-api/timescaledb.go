@@ -3,6 +3,8 @@ package ew_api
 import (
 	"errors"
 	"strings"
+
+	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
 )
 
 var timescaleDBTypeMap = map[string][2]string{
@@ -59,17 +61,10 @@ func genTimescaleDBExportArgs(args *TimescaleDBExportArgs, exportID string, dbNa
 		args.TimeFormat = timeFormat
 	}
 	args.TimeUnique = timeUnique
-	var shortExportID string
-	var shortDBName string
-	shortExportID, err = shortenId(exportID)
-	if err != nil {
-		return
-	}
-	shortDBName, err = shortenId(dbName)
+	args.TableName, err = util.TableName(exportID, dbName)
 	if err != nil {
 		return
 	}
-	args.TableName = "userid:" + shortDBName + "_export:" + shortExportID
 	var columns [][3]string
 	if len(dataFieldsMap) > 0 {
 		err = addColumns(&columns, dataFieldsMap, timePath)
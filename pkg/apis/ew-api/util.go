@@ -1,12 +1,12 @@
 package ew_api
 
 import (
-	"encoding/base64"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"strings"
 
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/service"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
 	permV2Client "github.com/SENERGY-Platform/permissions-v2/pkg/client"
@@ -25,9 +25,13 @@ const (
 	IdentKeyOperator = "operator_id"
 	IdentKeyImport   = "import_id"
 	InfluxDB         = "influxdb"
+	InfluxDB2        = "influxdb2"
 	TimescaleDB      = "timescaledb"
+	Postgres         = "postgres"
 )
 
+var databaseTypes = []string{InfluxDB, InfluxDB2, TimescaleDB, Postgres}
+
 func addIdentifier(identifiers *[]Identifier, key string, value string) {
 	*identifiers = append(*identifiers, Identifier{
 		Key:   key,
@@ -35,18 +39,23 @@ func addIdentifier(identifiers *[]Identifier, key string, value string) {
 	})
 }
 
-func genIdentifiers(identifiers *[]Identifier, filterType string, filter string, topic string) {
+func genIdentifiers(identifiers *[]Identifier, filterType string, filter string, topic string) (err error) {
 	switch filterType {
 	case TypeDevice:
 		addIdentifier(identifiers, IdentKeyDevice, filter)
 		addIdentifier(identifiers, IdentKeyService, strings.ReplaceAll(topic, "_", ":"))
 	case TypeAnalytics:
 		values := strings.Split(filter, ":")
+		if len(values) < 2 {
+			err = errors.New("filter must have the format '<pipeline_id>:<operator_id>'")
+			return
+		}
 		addIdentifier(identifiers, IdentKeyPipeline, values[0])
 		addIdentifier(identifiers, IdentKeyOperator, values[1])
 	case TypeImport:
 		addIdentifier(identifiers, IdentKeyImport, filter)
 	}
+	return
 }
 
 func genFieldsMap(fieldsMap *map[string]string, fields *string) (err error) {
@@ -61,17 +70,6 @@ func addMappings(mappings map[string]string, fieldsMap map[string]string, mappin
 	}
 }
 
-func shortenId(longId string) (string, error) {
-	parts := strings.Split(longId, ":")
-	noPrefix := parts[len(parts)-1]
-	noPrefix = strings.ReplaceAll(noPrefix, "-", "")
-	bytes, err := hex.DecodeString(noPrefix)
-	if err != nil {
-		return "", err
-	}
-	return base64.RawURLEncoding.EncodeToString(bytes), nil
-}
-
 func checkTopic(partitions *[]kafka.Partition, topic string) bool {
 	for _, p := range *partitions {
 		if p.Topic == topic {
@@ -91,8 +89,12 @@ func publishInstances(serving *service.Serving, missingIds *[]string) (err error
 		}
 		if len(instances) > 0 {
 			for _, instance := range instances {
+				switch instance.State {
+				case lib.InstanceStatePaused, lib.InstanceStateDeleting, lib.InstanceStateTrashed, lib.InstanceStateTruncating:
+					continue
+				}
 				util.Logger.Debug("publishing instance '" + instance.ID.String() + "' to '" + instance.ExportDatabase.EwFilterTopic + "'")
-				err = serving.CreateFromInstance(&instance)
+				err = serving.CreateFromInstance(db.DB, &instance)
 				if err != nil {
 					util.Logger.Error("failed to create from instance", "error", err)
 				}
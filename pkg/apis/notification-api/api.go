@@ -0,0 +1,57 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notification_api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/parnurzeal/gorequest"
+)
+
+type NotificationApi struct {
+	url string
+}
+
+type notification struct {
+	UserId  string `json:"userId"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	IsRead  bool   `json:"isRead"`
+	Topic   string `json:"topic"`
+}
+
+func NewNotificationApi(url string) *NotificationApi {
+	return &NotificationApi{url}
+}
+
+func (n *NotificationApi) SendNotification(userId string, title string, message string) (err error) {
+	request := gorequest.New()
+	resp, body, e := request.Post(n.url + "/notifications").Send(notification{
+		UserId:  userId,
+		Title:   title,
+		Message: message,
+		Topic:   "analytics",
+	}).End()
+	if len(e) > 0 {
+		return errors.New("notification API - could not send notification: " + errors.Join(e...).Error())
+	}
+	if resp.StatusCode >= 300 {
+		err = errors.New("notification API - could not send notification: " + strconv.Itoa(resp.StatusCode) + " " + body)
+	}
+	return
+}
@@ -36,6 +36,12 @@ type InfluxConfig struct {
 	Password string `json:"password" env_var:"INFLUX_DB_PASSWORD"`
 }
 
+type TimescaleConfig struct {
+	Url           string `json:"url" env_var:"TIMESCALE_URL"`
+	DeleteMode    string `json:"delete_mode" env_var:"TIMESCALE_DELETE_MODE"`
+	ArchiveSchema string `json:"archive_schema" env_var:"TIMESCALE_ARCHIVE_SCHEMA"`
+}
+
 type LoggerConfig struct {
 	Level string `json:"level" env_var:"LOGGER_LEVEL"`
 }
@@ -43,6 +49,11 @@ type LoggerConfig struct {
 type CleanupConfig struct {
 	WaitDuration string `json:"wait_duration" env_var:"CLEANUP_WAIT_DURATION"`
 	Cron         string `json:"cron" env_var:"CLEANUP_CRON"`
+	// PurgeDelay enables the trash if greater than zero, deleted exports are then purged after the delay.
+	PurgeDelay    string `json:"purge_delay" env_var:"PURGE_DELAY"`
+	PurgeCron     string `json:"purge_cron" env_var:"PURGE_CRON"`
+	ExpiryCron    string `json:"expiry_cron" env_var:"EXPIRY_CRON"`
+	ExpiryWarning string `json:"expiry_warning" env_var:"EXPIRY_WARNING"`
 }
 
 type KafkaConfig struct {
@@ -50,22 +61,51 @@ type KafkaConfig struct {
 	ReplicationFactor int    `json:"replication_factor" env_var:"KAFKA_REPLICATION_FACTOR"`
 }
 
+type OutboxConfig struct {
+	Interval    string `json:"interval" env_var:"OUTBOX_INTERVAL"`
+	BatchSize   int    `json:"batch_size" env_var:"OUTBOX_BATCH_SIZE"`
+	MaxAttempts int    `json:"max_attempts" env_var:"OUTBOX_MAX_ATTEMPTS"`
+	Retention   string `json:"retention" env_var:"OUTBOX_RETENTION"`
+}
+
+type JobConfig struct {
+	Workers      int    `json:"workers" env_var:"JOB_WORKERS"`
+	PollInterval string `json:"poll_interval" env_var:"JOB_POLL_INTERVAL"`
+	Retention    string `json:"retention" env_var:"JOB_RETENTION"`
+	// LeaseDuration is renewed while a job runs, jobs with an expired lease are run again by another worker.
+	LeaseDuration string `json:"lease_duration" env_var:"JOB_LEASE_DURATION"`
+}
+
+// CredentialsConfig holds the base64 encoded 32 byte keys used to encrypt export database credentials. The
+// previous keys are a comma separated list and only used to decrypt credentials until they are re-encrypted.
+// Without a key, credentials embedded in export database urls are kept in plain text and a warning is logged,
+// credentials given separately, like influxdb2 tokens, are rejected.
+type CredentialsConfig struct {
+	Key          string `json:"-" env_var:"CREDENTIALS_KEY"`
+	PreviousKeys string `json:"-" env_var:"CREDENTIALS_PREVIOUS_KEYS"`
+}
+
 type Config struct {
-	Logger                 LoggerConfig  `json:"logger" env_var:"LOGGER_CONFIG"`
-	URLPrefix              string        `json:"url_prefix" env_var:"URL_PREFIX"`
-	ServerPort             int           `json:"server_port" env_var:"SERVER_PORT"`
-	Debug                  bool          `json:"debug" env_var:"DEBUG"`
-	Driver                 string        `json:"driver" env_var:"DRIVER"`
-	MySQL                  MySQLConfig   `json:"mysql" env_var:"MYSQL_CONFIG"`
-	MigrationInfo          string        `json:"migration_info" env_var:"MIGRATION_INFO"`
-	Kafka                  KafkaConfig   `json:"kafka" env_var:"KAFKA_CONFIG"`
-	PermissionV2Url        string        `json:"permission_v2_url" env_var:"PERMISSION_V2_URL"`
-	PipelineApiUrl         string        `json:"pipeline_api_url" env_var:"PIPELINE_API_ENDPOINT"`
-	ImportDeployApiUrl     string        `json:"import_deploy_api_url" env_var:"IMPORT_DEPLOY_API_ENDPOINT"`
-	ExportDatabaseIdPrefix string        `json:"export_database_id_prefix" env_var:"EXPORT_DATABASE_ID_PREFIX"`
-	CleanupConfig          CleanupConfig `json:"cleanup_config" env_var:"CLEANUP_CONFIG"`
-	InfluxConfig           InfluxConfig  `json:"influx_config" env_var:"INFLUX_CONFIG"`
-	ApiDocsProviderBaseUrl string        `json:"api_docs_provider_base_url" env_var:"API_DOCS_PROVIDER_BASE_URL"`
+	Logger                 LoggerConfig      `json:"logger" env_var:"LOGGER_CONFIG"`
+	URLPrefix              string            `json:"url_prefix" env_var:"URL_PREFIX"`
+	ServerPort             int               `json:"server_port" env_var:"SERVER_PORT"`
+	Debug                  bool              `json:"debug" env_var:"DEBUG"`
+	Driver                 string            `json:"driver" env_var:"DRIVER"`
+	MySQL                  MySQLConfig       `json:"mysql" env_var:"MYSQL_CONFIG"`
+	MigrationInfo          string            `json:"migration_info" env_var:"MIGRATION_INFO"`
+	Kafka                  KafkaConfig       `json:"kafka" env_var:"KAFKA_CONFIG"`
+	Outbox                 OutboxConfig      `json:"outbox" env_var:"OUTBOX_CONFIG"`
+	PermissionV2Url        string            `json:"permission_v2_url" env_var:"PERMISSION_V2_URL"`
+	PipelineApiUrl         string            `json:"pipeline_api_url" env_var:"PIPELINE_API_ENDPOINT"`
+	ImportDeployApiUrl     string            `json:"import_deploy_api_url" env_var:"IMPORT_DEPLOY_API_ENDPOINT"`
+	NotificationUrl        string            `json:"notification_url" env_var:"NOTIFICATION_URL"`
+	Credentials            CredentialsConfig `json:"credentials" env_var:"CREDENTIALS_CONFIG"`
+	ExportDatabaseIdPrefix string            `json:"export_database_id_prefix" env_var:"EXPORT_DATABASE_ID_PREFIX"`
+	CleanupConfig          CleanupConfig     `json:"cleanup_config" env_var:"CLEANUP_CONFIG"`
+	JobConfig              JobConfig         `json:"job_config" env_var:"JOB_CONFIG"`
+	InfluxConfig           InfluxConfig      `json:"influx_config" env_var:"INFLUX_CONFIG"`
+	TimescaleConfig        TimescaleConfig   `json:"timescale_config" env_var:"TIMESCALE_CONFIG"`
+	ApiDocsProviderBaseUrl string            `json:"api_docs_provider_base_url" env_var:"API_DOCS_PROVIDER_BASE_URL"`
 }
 
 func New(path string) (*Config, error) {
@@ -88,15 +128,35 @@ func New(path string) (*Config, error) {
 			User:     "root",
 			Password: "",
 		},
+		TimescaleConfig: TimescaleConfig{
+			DeleteMode:    "drop",
+			ArchiveSchema: "archive",
+		},
 		MigrationInfo: "",
 		Kafka: KafkaConfig{
 			Bootstrap:         "localhost:9092",
 			ReplicationFactor: 2,
 		},
+		Outbox: OutboxConfig{
+			Interval:    "1s",
+			BatchSize:   100,
+			MaxAttempts: 12,
+			Retention:   "24h",
+		},
 		ExportDatabaseIdPrefix: "",
 		CleanupConfig: CleanupConfig{
-			WaitDuration: "10s",
-			Cron:         "0 1 * * *",
+			WaitDuration:  "10s",
+			Cron:          "0 1 * * *",
+			PurgeDelay:    "0s",
+			PurgeCron:     "*/15 * * * *",
+			ExpiryCron:    "*/5 * * * *",
+			ExpiryWarning: "24h",
+		},
+		JobConfig: JobConfig{
+			Workers:       2,
+			PollInterval:  "5s",
+			Retention:     "168h",
+			LeaseDuration: "1m",
 		},
 		ApiDocsProviderBaseUrl: "",
 	}
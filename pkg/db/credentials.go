@@ -0,0 +1,293 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+var ErrCredentialsKeyMissing = errors.New("no credentials key configured")
+
+// ExportDatabaseCredentials holds the encrypted credentials of an export database. KeyId identifies the key
+// the credentials were encrypted with, so rows can be re-encrypted after the key was rotated.
+type ExportDatabaseCredentials struct {
+	ExportDatabaseID string `gorm:"primary_key;type:varchar(255);column:export_database_id"`
+	KeyId            string `gorm:"type:varchar(16)"`
+	Data             string `gorm:"type:text"`
+	UpdatedAt        time.Time
+}
+
+type credentialsKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+var currentKey *credentialsKey
+var keys = map[string]*credentialsKey{}
+
+// InitCredentials loads the current and previous credentials keys. Keys are base64 encoded and 32 bytes long.
+func InitCredentials(cfg config.CredentialsConfig) (err error) {
+	currentKey = nil
+	keys = map[string]*credentialsKey{}
+	if cfg.Key == "" {
+		return
+	}
+	currentKey, err = newCredentialsKey(cfg.Key)
+	if err != nil {
+		return
+	}
+	keys[currentKey.id] = currentKey
+	for _, raw := range strings.Split(cfg.PreviousKeys, ",") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		key, err := newCredentialsKey(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		keys[key.id] = key
+	}
+	return
+}
+
+func newCredentialsKey(raw string) (*credentialsKey, error) {
+	b, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errors.New("invalid credentials key - " + err.Error())
+	}
+	if len(b) != 32 {
+		return nil, errors.New("invalid credentials key - expected 32 bytes")
+	}
+	block, err := aes.NewCipher(b)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	return &credentialsKey{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+// SaveCredentials encrypts and stores the credentials of an export database. Empty credentials are removed.
+func SaveCredentials(tx *gorm.DB, exportDatabaseId string, credentials lib.Credentials) error {
+	if credentials.IsEmpty() {
+		return tx.Where("export_database_id = ?", exportDatabaseId).Delete(&ExportDatabaseCredentials{}).Error
+	}
+	if currentKey == nil {
+		return ErrCredentialsKeyMissing
+	}
+	data, err := encryptCredentials(currentKey, credentials)
+	if err != nil {
+		return err
+	}
+	return tx.Save(&ExportDatabaseCredentials{ExportDatabaseID: exportDatabaseId, KeyId: currentKey.id, Data: data}).Error
+}
+
+// LoadCredentials decrypts the stored credentials of an export database into database.Credentials.
+func LoadCredentials(database *lib.ExportDatabase) error {
+	var stored ExportDatabaseCredentials
+	err := DB.Where("export_database_id = ?", database.ID).First(&stored).Error
+	if gorm.IsRecordNotFoundError(err) {
+		database.Credentials = &lib.Credentials{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	credentials, err := decryptCredentials(stored)
+	if err != nil {
+		return err
+	}
+	database.Credentials = &credentials
+	return nil
+}
+
+// CredentialsStored returns the ids of the given export databases that have stored credentials.
+func CredentialsStored(exportDatabaseIds []string) (stored map[string]bool, err error) {
+	stored = map[string]bool{}
+	if len(exportDatabaseIds) == 0 {
+		return
+	}
+	var ids []string
+	err = DB.Model(&ExportDatabaseCredentials{}).Where("export_database_id IN (?)", exportDatabaseIds).Pluck("export_database_id", &ids).Error
+	for _, id := range ids {
+		stored[id] = true
+	}
+	return
+}
+
+func DeleteCredentials(tx *gorm.DB, exportDatabaseId string) error {
+	return tx.Where("export_database_id = ?", exportDatabaseId).Delete(&ExportDatabaseCredentials{}).Error
+}
+
+// ExtractUrlCredentials removes user info from an url and returns it as credentials.
+func ExtractUrlCredentials(rawUrl string) (stripped string, credentials lib.Credentials) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.User == nil {
+		return rawUrl, credentials
+	}
+	credentials.Username = u.User.Username()
+	credentials.Password, _ = u.User.Password()
+	u.User = nil
+	return u.String(), credentials
+}
+
+// MigrateCredentials moves plain text tokens and url credentials into the encrypted store and re-encrypts
+// credentials of previous keys with the current key. The legacy token column is dropped once it is migrated.
+func MigrateCredentials() (err error) {
+	tokens, err := legacyTokens()
+	if err != nil {
+		return
+	}
+	if currentKey == nil {
+		var count int
+		err = DB.Model(&lib.ExportDatabase{}).Where("url LIKE '%@%'").Count(&count).Error
+		if err == nil && count+len(tokens) > 0 {
+			util.Logger.Warn("export-databases with plain text credentials found, configure CREDENTIALS_KEY to encrypt them", "count", count+len(tokens))
+		}
+		return
+	}
+	query := DB.Where("url LIKE '%@%'")
+	if len(tokens) > 0 {
+		var ids []string
+		for id := range tokens {
+			ids = append(ids, id)
+		}
+		query = query.Or("id IN (?)", ids)
+	}
+	var databases []lib.ExportDatabase
+	err = query.Find(&databases).Error
+	if err != nil {
+		return
+	}
+	for _, database := range databases {
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			if e := LoadCredentials(&database); e != nil {
+				return e
+			}
+			credentials := *database.Credentials
+			var fromUrl lib.Credentials
+			database.Url, fromUrl = ExtractUrlCredentials(database.Url)
+			credentials.Merge(fromUrl)
+			credentials.Merge(lib.Credentials{Token: tokens[database.ID]})
+			if e := SaveCredentials(tx, database.ID, credentials); e != nil {
+				return e
+			}
+			return tx.Model(&lib.ExportDatabase{}).Where("id = ?", database.ID).UpdateColumn("url", database.Url).Error
+		})
+		if err != nil {
+			return
+		}
+		util.Logger.Info("moved plain text credentials into encrypted store", "export_database_id", database.ID)
+	}
+	if DB.Dialect().HasColumn("export_databases", "token") {
+		err = DB.Model(&lib.ExportDatabase{}).DropColumn("token").Error
+		if err != nil {
+			return
+		}
+	}
+	var outdated []ExportDatabaseCredentials
+	err = DB.Where("key_id <> ?", currentKey.id).Find(&outdated).Error
+	if err != nil {
+		return
+	}
+	for _, stored := range outdated {
+		credentials, err := decryptCredentials(stored)
+		if err != nil {
+			return err
+		}
+		err = SaveCredentials(DB, stored.ExportDatabaseID, credentials)
+		if err != nil {
+			return err
+		}
+	}
+	if len(outdated) > 0 {
+		util.Logger.Info("re-encrypted export-database credentials with current key", "count", len(outdated))
+	}
+	return
+}
+
+func encryptCredentials(key *credentialsKey, credentials lib.Credentials) (string, error) {
+	plain, err := json.Marshal(credentials)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key.aead.Seal(nonce, nonce, plain, nil)), nil
+}
+
+func decryptCredentials(stored ExportDatabaseCredentials) (credentials lib.Credentials, err error) {
+	key, ok := keys[stored.KeyId]
+	if !ok {
+		return credentials, errors.New("unknown credentials key '" + stored.KeyId + "' for export-database " + stored.ExportDatabaseID)
+	}
+	data, err := base64.StdEncoding.DecodeString(stored.Data)
+	if err != nil {
+		return
+	}
+	size := key.aead.NonceSize()
+	if len(data) < size {
+		return credentials, errors.New("invalid credentials of export-database " + stored.ExportDatabaseID)
+	}
+	plain, err := key.aead.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(plain, &credentials)
+	return
+}
+
+func CredentialsConfigured() bool {
+	return currentKey != nil
+}
+
+// legacyTokens returns the plain text influxdb2 tokens of the former token column by export-database id.
+func legacyTokens() (tokens map[string]string, err error) {
+	tokens = map[string]string{}
+	if !DB.Dialect().HasColumn("export_databases", "token") {
+		return
+	}
+	var rows []struct {
+		ID    string
+		Token string
+	}
+	err = DB.Table("export_databases").Select("id, token").Where("token <> ''").Scan(&rows).Error
+	for _, row := range rows {
+		tokens[row.ID] = row.Token
+	}
+	return
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
+)
+
+func newTestKey(t *testing.T) string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestCredentialsEncryption(t *testing.T) {
+	defer InitCredentials(config.CredentialsConfig{})
+	oldKey := newTestKey(t)
+	newKey := newTestKey(t)
+	credentials := lib.Credentials{Username: "user", Password: "pw", Token: "token"}
+
+	err := InitCredentials(config.CredentialsConfig{Key: oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encryptCredentials(currentKey, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := ExportDatabaseCredentials{ExportDatabaseID: "db", KeyId: currentKey.id, Data: data}
+
+	t.Run("round trip", func(t *testing.T) {
+		result, err := decryptCredentials(stored)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result != credentials {
+			t.Error("unexpected credentials", result)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		err := InitCredentials(config.CredentialsConfig{Key: newKey})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err = decryptCredentials(stored); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("previous key", func(t *testing.T) {
+		err := InitCredentials(config.CredentialsConfig{Key: newKey, PreviousKeys: " ," + oldKey})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		result, err := decryptCredentials(stored)
+		if err != nil || result != credentials {
+			t.Error("unexpected credentials", result, err)
+		}
+	})
+
+	t.Run("wrong key with known id", func(t *testing.T) {
+		err := InitCredentials(config.CredentialsConfig{Key: newKey})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err = decryptCredentials(ExportDatabaseCredentials{ExportDatabaseID: "db", KeyId: currentKey.id, Data: data}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("tampered data", func(t *testing.T) {
+		b, _ := base64.StdEncoding.DecodeString(data)
+		b[len(b)-1] ^= 1
+		tampered := stored
+		tampered.Data = base64.StdEncoding.EncodeToString(b)
+		err := InitCredentials(config.CredentialsConfig{Key: oldKey})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err = decryptCredentials(tampered); err == nil {
+			t.Error("expected error")
+		}
+		tampered.Data = "AAAA"
+		if _, err = decryptCredentials(tampered); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestInitCredentialsInvalidKey(t *testing.T) {
+	defer InitCredentials(config.CredentialsConfig{})
+	for _, key := range []string{"not base64", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if err := InitCredentials(config.CredentialsConfig{Key: key}); err == nil {
+			t.Error("expected error for key", key)
+		}
+	}
+	if err := InitCredentials(config.CredentialsConfig{Key: newTestKey(t), PreviousKeys: "invalid"}); err == nil {
+		t.Error("expected error for previous key")
+	}
+	if err := InitCredentials(config.CredentialsConfig{}); err != nil || CredentialsConfigured() {
+		t.Error("expected no key", err)
+	}
+}
+
+func TestExtractUrlCredentials(t *testing.T) {
+	tests := []struct {
+		url         string
+		stripped    string
+		credentials lib.Credentials
+	}{
+		{"http://user:pw@influx:8086", "http://influx:8086", lib.Credentials{Username: "user", Password: "pw"}},
+		{"postgres://user@host:5432/db?sslmode=disable", "postgres://host:5432/db?sslmode=disable", lib.Credentials{Username: "user"}},
+		{"http://influx:8086", "http://influx:8086", lib.Credentials{}},
+		{"host=db user=u", "host=db user=u", lib.Credentials{}},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			stripped, credentials := ExtractUrlCredentials(test.url)
+			if stripped != test.stripped || credentials != test.credentials {
+				t.Errorf("expected %s %+v, got %s %+v", test.stripped, test.credentials, stripped, credentials)
+			}
+		})
+	}
+}
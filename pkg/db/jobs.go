@@ -0,0 +1,86 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db
+
+import (
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// ClaimNextJob leases the oldest pending job to workerId. Running jobs whose lease expired, because their worker
+// stopped, are claimed again. Jobs of an export run one after another. The returned job is nil if none is runnable.
+func ClaimNextJob(workerId string, lease time.Duration) (*lib.Job, error) {
+	for {
+		now := time.Now()
+		var job lib.Job
+		leased := DB.Table("jobs").Select("instance_id").Where("state = ? AND lease_until >= ?", lib.JobStateRunning, now).SubQuery()
+		err := DB.Where("(state = ? OR (state = ? AND (lease_until IS NULL OR lease_until < ?))) AND instance_id NOT IN (?)",
+			lib.JobStatePending, lib.JobStateRunning, now, leased).Order("created_at asc").First(&job).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		leaseUntil := now.Add(lease)
+		result := DB.Model(&lib.Job{}).Where("id = ? AND state = ? AND (lease_until IS NULL OR lease_until < ?)", job.ID, job.State, now).
+			UpdateColumns(map[string]interface{}{
+				"state":       lib.JobStateRunning,
+				"worker_id":   workerId,
+				"lease_until": leaseUntil,
+				"started_at":  now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			//claimed by another worker
+			continue
+		}
+		job.State = lib.JobStateRunning
+		job.WorkerId = workerId
+		job.LeaseUntil = &leaseUntil
+		job.StartedAt = &now
+		return &job, nil
+	}
+}
+
+// ExtendJobLease renews the lease of a running job. It returns false if the job is no longer leased by workerId.
+func ExtendJobLease(id uuid.UUID, workerId string, lease time.Duration) (bool, error) {
+	result := DB.Model(&lib.Job{}).Where("id = ? AND state = ? AND worker_id = ?", id, lib.JobStateRunning, workerId).
+		UpdateColumn("lease_until", time.Now().Add(lease))
+	return result.RowsAffected > 0, result.Error
+}
+
+// FinishJob stores the result of a job unless its lease has been taken over by another worker.
+func FinishJob(id uuid.UUID, workerId string, cause error) (bool, error) {
+	fields := map[string]interface{}{"state": lib.JobStateDone, "progress": 100, "finished_at": time.Now(), "lease_until": nil}
+	if cause != nil {
+		fields["state"] = lib.JobStateFailed
+		fields["error"] = cause.Error()
+	}
+	result := DB.Model(&lib.Job{}).Where("id = ? AND state = ? AND worker_id = ?", id, lib.JobStateRunning, workerId).UpdateColumns(fields)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteFinishedJobs removes done and failed jobs that finished before the given time.
+func DeleteFinishedJobs(before time.Time) error {
+	return DB.Where("state IN (?) AND finished_at < ?", []string{lib.JobStateDone, lib.JobStateFailed}, before).Delete(&lib.Job{}).Error
+}
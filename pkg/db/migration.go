@@ -53,6 +53,58 @@ func (m *Migration) Migrate() {
 		DB.CreateTable(&lib.ExportDatabase{})
 	}
 	DB.AutoMigrate(&lib.ExportDatabase{})
+	if !DB.HasTable("instance_revisions") {
+		util.Logger.Debug("Creating instance_revisions table.")
+		DB.CreateTable(&lib.InstanceRevision{})
+	}
+	DB.AutoMigrate(&lib.InstanceRevision{})
+	if !DB.HasTable("jobs") {
+		util.Logger.Debug("Creating jobs table.")
+		DB.CreateTable(&lib.Job{})
+	}
+	DB.AutoMigrate(&lib.Job{})
+	if !DB.HasTable("outbox_messages") {
+		util.Logger.Debug("Creating outbox_messages table.")
+		DB.CreateTable(&OutboxMessage{})
+	}
+	DB.AutoMigrate(&OutboxMessage{})
+	if !DB.HasTable("export_database_credentials") {
+		util.Logger.Debug("Creating export_database_credentials table.")
+		DB.CreateTable(&ExportDatabaseCredentials{})
+	}
+	DB.AutoMigrate(&ExportDatabaseCredentials{})
+}
+
+// MigrateOffsets stores the start offsets of exports as "earliest", "latest" or a RFC3339 timestamp. Relative offsets
+// are resolved from the last update of an export, unknown legacy values are replaced by "latest". Only offsets that
+// are not stored in this form yet are selected, every rewrite is logged.
+func MigrateOffsets() (err error) {
+	var instances []lib.Instance
+	err = DB.Select("id, `offset`, updated_at").
+		Where("`offset` NOT IN (?) AND `offset` NOT REGEXP ?", []string{util.OffsetEarliest, util.OffsetLatest}, "^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}Z$").
+		Find(&instances).Error
+	if err != nil {
+		return
+	}
+	for _, instance := range instances {
+		offset, e := util.ResolveOffset(instance.Offset, instance.UpdatedAt)
+		if e != nil {
+			offset = util.OffsetLatest
+		}
+		if offset == instance.Offset {
+			continue
+		}
+		err = DB.Model(&lib.Instance{}).Where("id = ?", instance.ID).UpdateColumn("offset", offset).Error
+		if err != nil {
+			return
+		}
+		if e != nil {
+			util.Logger.Warn("replaced invalid export offset", "id", instance.ID.String(), "old", instance.Offset, "new", offset)
+			continue
+		}
+		util.Logger.Info("migrated export offset", "id", instance.ID.String(), "old", instance.Offset, "new", offset)
+	}
+	return
 }
 
 type MigrationInfo struct {
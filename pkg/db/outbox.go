@@ -0,0 +1,137 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/jinzhu/gorm"
+)
+
+// OutboxMessage is a message that has to be published to kafka. Messages are written in the same
+// transaction as the instance change they belong to and published by a relay afterwards. Failed messages
+// are retried after NextAttemptAt and parked once they reach the maximum attempts.
+type OutboxMessage struct {
+	ID            uint64 `gorm:"primary_key;AUTO_INCREMENT"`
+	Topic         string `gorm:"type:varchar(255)"`
+	Key           string `gorm:"type:varchar(255)"`
+	Payload       string `gorm:"type:mediumtext"`
+	Method        string `gorm:"type:varchar(32)"`
+	InstanceID    string `gorm:"type:char(36);index"`
+	Attempts      int    `gorm:"DEFAULT:0"`
+	LastError     string `gorm:"type:text"`
+	NextAttemptAt *time.Time
+	SentAt        *time.Time `gorm:"index"`
+	ParkedAt      *time.Time `gorm:"index"`
+	CreatedAt     time.Time
+}
+
+func AddOutboxMessage(tx *gorm.DB, message *OutboxMessage) error {
+	message.ID = 0
+	message.SentAt = nil
+	return tx.Create(message).Error
+}
+
+// GetPendingOutboxMessages returns unsent messages that are due. Messages of a key wait while an earlier
+// message of the same key waits for its retry, so the messages of an instance stay in order.
+func GetPendingOutboxMessages(limit int) (messages []OutboxMessage, err error) {
+	now := time.Now()
+	err = DB.Where("sent_at IS NULL AND parked_at IS NULL AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", now).
+		Where("NOT EXISTS (SELECT 1 FROM outbox_messages o WHERE o.`key` = outbox_messages.`key` AND o.id < outbox_messages.id "+
+			"AND o.sent_at IS NULL AND o.parked_at IS NULL AND o.next_attempt_at > ?)", now).
+		Order("id asc").Limit(limit).Find(&messages).Error
+	return
+}
+
+// MarkOutboxMessagesSent marks messages as published and activates pending or failed instances
+// whose filter has been published.
+func MarkOutboxMessagesSent(messages []OutboxMessage, putMethod string) error {
+	var ids []uint64
+	var instanceIds []string
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+		if message.Method == putMethod && message.InstanceID != "" {
+			instanceIds = append(instanceIds, message.InstanceID)
+		}
+	}
+	now := time.Now()
+	tx := DB.Begin()
+	err := tx.Model(&OutboxMessage{}).Where("id IN (?)", ids).UpdateColumn("sent_at", now).Error
+	if err == nil && len(instanceIds) > 0 {
+		err = tx.Model(&lib.Instance{}).
+			Where("id IN (?) AND state IN (?)", instanceIds, []string{lib.InstanceStatePending, lib.InstanceStateFailed}).
+			UpdateColumns(map[string]interface{}{"state": lib.InstanceStateActive, "state_changed_at": now}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// SetOutboxMessageError records a failed publish attempt and schedules the next one at retryAt. Messages that
+// reached maxAttempts are parked and no longer published, the instance they belong to is marked as failed.
+func SetOutboxMessageError(message OutboxMessage, cause error, maxAttempts int, retryAt time.Time) error {
+	now := time.Now()
+	fields := map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      cause.Error(),
+		"next_attempt_at": retryAt,
+	}
+	parked := message.Attempts+1 >= maxAttempts
+	if parked {
+		fields["parked_at"] = now
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&OutboxMessage{}).Where("id = ?", message.ID).UpdateColumns(fields).Error
+		if err != nil || !parked || message.InstanceID == "" {
+			return err
+		}
+		return tx.Model(&lib.Instance{}).
+			Where("id = ? AND state = ?", message.InstanceID, lib.InstanceStatePending).
+			UpdateColumns(map[string]interface{}{
+				"state":            lib.InstanceStateFailed,
+				"state_changed_at": now,
+				"last_error":       cause.Error(),
+				"last_error_at":    now,
+			}).Error
+	})
+}
+
+// OutboxDelivered reports whether the latest message of an instance has been published. It returns an error if
+// the message has been parked.
+func OutboxDelivered(instanceId string) (bool, error) {
+	var latest OutboxMessage
+	err := DB.Where("instance_id = ?", instanceId).Order("id desc").First(&latest).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if latest.ParkedAt != nil {
+		return false, errors.New("outbox message parked - " + latest.LastError)
+	}
+	return latest.SentAt != nil, nil
+}
+
+// DeleteSentOutboxMessages removes messages that were sent or parked before the given time.
+func DeleteSentOutboxMessages(before time.Time) error {
+	return DB.Where("sent_at < ? OR parked_at < ?", before, before).Delete(&OutboxMessage{}).Error
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	"github.com/google/uuid"
+)
+
+// Apply reconciles the exports selected by args with the desired exports of a bundle. Exports are matched by name,
+// missing ones are created and changed ones updated. Selected exports of the user that are not desired anymore are
+// only deleted if args select exports or prune is set. Missing export databases of the bundle are created, export
+// databases are never deleted. Relative offsets only position created exports, existing exports keep their offset.
+// With dryRun the actions are only computed.
+func (f *Serving) Apply(bundle lib.Bundle, args map[string][]string, prune bool, dryRun bool, userId string, token string) (result lib.ApplyResult, err error) {
+	desiredNames := map[string]bool{}
+	for _, export := range bundle.Exports {
+		if desiredNames[export.Name] {
+			return result, fmt.Errorf("%w: duplicate export name '%s'", ErrInvalidExport, export.Name)
+		}
+		desiredNames[export.Name] = true
+	}
+	databases, errs := f.GetExportDatabases(userId, nil, token)
+	if len(errs) > 0 {
+		return result, errors.Join(errs...)
+	}
+	current, _, errs := f.GetInstancesForUser(userId, args, token)
+	if len(errs) > 0 {
+		return result, errors.Join(errs...)
+	}
+	databaseIds := map[string]string{}
+	for _, database := range databases {
+		if _, ok := databaseIds[database.Name]; !ok || database.UserId == userId {
+			databaseIds[database.Name] = database.ID
+		}
+	}
+	currentByName := map[string]lib.Instance{}
+	for _, instance := range current {
+		currentByName[instance.Name] = instance
+	}
+	result = lib.ApplyResult{DryRun: dryRun, Actions: []lib.ApplyAction{}}
+
+	for _, request := range bundle.ExportDatabases {
+		if _, ok := databaseIds[request.Name]; ok {
+			continue
+		}
+		action := lib.ApplyAction{Kind: lib.ApplyKindExportDatabase, Action: lib.ApplyActionCreate, Name: request.Name}
+		databaseIds[request.Name] = ""
+		if !dryRun {
+			database, errs := f.CreateExportDatabase("", request, userId)
+			if len(errs) > 0 {
+				action.Error = errors.Join(errs...).Error()
+			} else {
+				action.Applied = true
+				action.ID = database.ID
+				databaseIds[request.Name] = database.ID
+			}
+		}
+		result.Actions = append(result.Actions, action)
+	}
+
+	for _, export := range bundle.Exports {
+		action := lib.ApplyAction{Kind: lib.ApplyKindExport, Action: lib.ApplyActionCreate, Name: export.Name}
+		request := export.ServingRequest
+		databaseId, ok := databaseIds[export.ExportDatabase]
+		request.ExportDatabaseID = databaseId
+		instance, exists := currentByName[export.Name]
+		if exists {
+			action.ID = instance.ID.String()
+			action.Action = lib.ApplyActionUpdate
+		}
+		if exists && ok {
+			request.Offset = appliedOffset(instance.Offset, request.Offset)
+			compared := request
+			if databaseId == "" {
+				//export database is only created in a dry run, compare it by name
+				compared.ExportDatabaseID = instance.ExportDatabaseID
+			}
+			action.Changes = revisionChanges(servingRequestFromInstance(instance), compared)
+			if databaseId == "" {
+				action.Changes = append(action.Changes, lib.RevisionChange{Field: "ExportDatabase", Old: instance.ExportDatabase.Name, New: export.ExportDatabase})
+			}
+			if len(action.Changes) == 0 {
+				action.Action = lib.ApplyActionNone
+			}
+		}
+		switch {
+		case !ok || (databaseId == "" && !dryRun):
+			action.Error = "unknown export database '" + export.ExportDatabase + "'"
+		case dryRun || action.Action == lib.ApplyActionNone:
+		case action.Action == lib.ApplyActionCreate:
+			created, err := f.CreateInstance(request, userId, token)
+			if err == nil && created.ID == uuid.Nil {
+				err = errors.New("serving - unsupported filter type '" + request.FilterType + "'")
+			}
+			if err != nil {
+				action.Error = err.Error()
+			} else {
+				action.Applied = true
+				action.ID = created.ID.String()
+			}
+		case action.Action == lib.ApplyActionUpdate:
+			_, errs := f.UpdateInstance(action.ID, userId, request, token)
+			if len(errs) > 0 {
+				action.Error = errors.Join(errs...).Error()
+			} else {
+				action.Applied = true
+			}
+		}
+		result.Actions = append(result.Actions, action)
+	}
+
+	if !prune && len(args) == 0 {
+		return
+	}
+	for _, instance := range current {
+		if desiredNames[instance.Name] || instance.UserId != userId || instance.State == lib.InstanceStateTrashed {
+			continue
+		}
+		action := lib.ApplyAction{Kind: lib.ApplyKindExport, Action: lib.ApplyActionDelete, Name: instance.Name, ID: instance.ID.String()}
+		if !dryRun {
+			deleted, errs := f.DeleteInstanceWithPermHandling(action.ID, userId, false, token)
+			if len(errs) > 0 {
+				action.Error = errors.Join(errs...).Error()
+			}
+			action.Applied = deleted
+		}
+		result.Actions = append(result.Actions, action)
+	}
+	return
+}
+
+// appliedOffset returns the offset an existing export is compared with and updated to. Offsets are stored resolved,
+// so the bundle offset is resolved the same way. A relative offset would resolve to a new start on every apply and
+// replay the export, the stored offset is kept instead.
+func appliedOffset(stored string, offset string) string {
+	if util.IsRelativeOffset(offset) {
+		return stored
+	}
+	if resolved, err := util.ResolveOffset(offset, time.Now()); err == nil {
+		return resolved
+	}
+	return offset
+}
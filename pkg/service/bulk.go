@@ -0,0 +1,182 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"errors"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	permV2Client "github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"github.com/google/uuid"
+)
+
+// CreateInstances creates multiple exports. Source access is checked once for all requests and the permissions of all
+// exports are written with a single import. Every export is then stored in its own transaction, so one invalid request
+// does not fail the others, and its filter message is published by the outbox relay together with the others.
+func (f *Serving) CreateInstances(requests []lib.ServingRequest, userId string, token string) (results []lib.BulkResult) {
+	errs := f.userHasSourceAccessBulk(requests, token)
+	ids := make([]uuid.UUID, len(requests))
+	offsets := make([]string, len(requests))
+	var resources []permV2Client.Resource
+	for i := range requests {
+		if errs[i] == nil {
+			offsets[i], errs[i] = resolveOffset(requests[i].Offset)
+		}
+		if errs[i] != nil {
+			continue
+		}
+		ids[i] = uuid.New()
+		resources = append(resources, permV2Client.Resource{
+			Id:                  ids[i].String(),
+			TopicId:             ExportInstancePermissionsTopic,
+			ResourcePermissions: instancePermissions(userId),
+		})
+	}
+	if f.permissionsV2 != nil {
+		f.permMux.RLock()
+		defer f.permMux.RUnlock()
+		if len(resources) > 0 {
+			err, _ := f.permissionsV2.Import(permV2Client.InternalAdminToken, permV2Client.ImportExport{Permissions: resources}, permV2Client.ImportExportOptions{
+				IncludePermissions: true,
+				FilterTopics:       []string{ExportInstancePermissionsTopic},
+			})
+			if err != nil {
+				for i := range errs {
+					if errs[i] == nil {
+						errs[i] = err
+					}
+				}
+			}
+		}
+	}
+	for i, request := range requests {
+		result := lib.BulkResult{Index: i, Status: lib.BulkStatusFailed}
+		if errs[i] != nil {
+			result.Error = errs[i].Error()
+			results = append(results, result)
+			continue
+		}
+		request.Offset = offsets[i]
+		instance, err := f.createInstanceWithId(ids[i], uuid.New(), request, userId, userId, nil, token)
+		if err != nil {
+			f.removeInconsistentPermission(ids[i].String(), err)
+			result.Error = err.Error()
+		} else {
+			result.ID = instance.ID.String()
+			result.Status = lib.BulkStatusCreated
+			result.Instance = &instance
+		}
+		results = append(results, result)
+	}
+	return
+}
+
+// UpdateInstances updates multiple exports. Source and write access are checked once for all requests.
+func (f *Serving) UpdateInstances(requests []lib.BulkUpdateRequest, userId string, token string) (results []lib.BulkResult) {
+	servingRequests := []lib.ServingRequest{}
+	ids := []string{}
+	for _, request := range requests {
+		servingRequests = append(servingRequests, request.ServingRequest)
+		ids = append(ids, request.ID)
+	}
+	accessErrs := f.userHasSourceAccessBulk(servingRequests, token)
+	writable, err := f.writableInstanceIds(ids, userId, token)
+	for i, request := range requests {
+		result := lib.BulkResult{Index: i, ID: request.ID, Status: lib.BulkStatusFailed}
+		if err == nil {
+			err = accessErrs[i]
+		}
+		if err == nil && !writable[request.ID] {
+			err = errors.New("access denied")
+		}
+		var instance lib.Instance
+		if err == nil {
+			instance, err = f.getInstanceById(request.ID)
+		}
+		if err == nil {
+			var errs []error
+			instance, errs = f.updateInstance(instance, userId, request.ServingRequest, token)
+			err = errors.Join(errs...)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Status = lib.BulkStatusUpdated
+			result.Instance = &instance
+		}
+		results = append(results, result)
+		err = nil
+	}
+	return
+}
+
+func (f *Serving) writableInstanceIds(ids []string, userId string, token string) (writable map[string]bool, err error) {
+	if len(ids) == 0 {
+		return map[string]bool{}, nil
+	}
+	if f.permissionsV2 != nil {
+		writable, err, _ = f.permissionsV2.CheckMultiplePermissions(token, ExportInstancePermissionsTopic, ids, permV2Client.Write)
+		return
+	}
+	var instances lib.Instances
+	err = db.DB.Select("id").Where("id IN (?) AND user_id = ?", ids, userId).Find(&instances).Error
+	if err != nil {
+		return
+	}
+	writable = map[string]bool{}
+	for _, instance := range instances {
+		writable[instance.ID.String()] = true
+	}
+	return
+}
+
+// userHasSourceAccessBulk checks the device access of all requests with a single permissions request, other sources
+// are checked once per distinct filter.
+func (f *Serving) userHasSourceAccessBulk(requests []lib.ServingRequest, token string) (errs []error) {
+	errs = make([]error, len(requests))
+	var deviceIds []string
+	for _, request := range requests {
+		if request.FilterType == "deviceId" {
+			deviceIds = append(deviceIds, request.Filter)
+		}
+	}
+	var deviceAccess map[string]bool
+	var deviceErr error
+	if len(deviceIds) > 0 {
+		deviceAccess, deviceErr, _ = f.permissionsV2.CheckMultiplePermissions(token, PermV2DeviceTopic, deviceIds, permV2Client.Read)
+	}
+	checked := map[string]error{}
+	for i, request := range requests {
+		if request.FilterType == "deviceId" {
+			if deviceErr != nil {
+				errs[i] = deviceErr
+			} else if !deviceAccess[request.Filter] {
+				errs[i] = errors.New("serving - user does not have the rights to access the devices")
+			}
+			continue
+		}
+		key := request.FilterType + ":" + request.Filter
+		err, ok := checked[key]
+		if !ok {
+			err = f.checkSourceAccess(request, token)
+			checked[key] = err
+		}
+		errs[i] = err
+	}
+	return
+}
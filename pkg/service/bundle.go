@@ -0,0 +1,160 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"errors"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/google/uuid"
+)
+
+// ExportBundle returns the exports and export databases owned by a user without ids. Exports
+// reference their export database by name. Credentials are not exported, the affected export databases are listed
+// in CredentialsRequired.
+func (f *Serving) ExportBundle(userId string, token string) (bundle lib.Bundle, err error) {
+	databases, errs := f.GetExportDatabases(userId, nil, token)
+	if len(errs) > 0 {
+		return bundle, errors.Join(errs...)
+	}
+	var databaseIds []string
+	for _, database := range databases {
+		databaseIds = append(databaseIds, database.ID)
+	}
+	withStoredCredentials, err := db.CredentialsStored(databaseIds)
+	if err != nil {
+		return
+	}
+	instances, _, errs := f.GetInstancesForUser(userId, map[string][]string{}, token)
+	if len(errs) > 0 {
+		return bundle, errors.Join(errs...)
+	}
+	bundle = lib.Bundle{ExportDatabases: []lib.ExportDatabaseRequest{}, Exports: []lib.BundleExport{}}
+	for _, database := range databases {
+		if database.UserId != userId {
+			continue
+		}
+		url, urlCredentials := db.ExtractUrlCredentials(database.Url)
+		if withStoredCredentials[database.ID] || !urlCredentials.IsEmpty() {
+			bundle.CredentialsRequired = append(bundle.CredentialsRequired, database.Name)
+		}
+		bundle.ExportDatabases = append(bundle.ExportDatabases, lib.ExportDatabaseRequest{
+			Name:          database.Name,
+			Description:   database.Description,
+			Type:          database.Type,
+			Deployment:    database.Deployment,
+			Url:           url,
+			EwFilterTopic: database.EwFilterTopic,
+			Public:        database.Public,
+			Org:           database.Org,
+			Bucket:        database.Bucket,
+		})
+	}
+	for _, instance := range instances {
+		//exports shared with the user belong into the bundle of their owner
+		if instance.UserId != userId {
+			continue
+		}
+		request := servingRequestFromInstance(instance)
+		request.ExportDatabaseID = ""
+		bundle.Exports = append(bundle.Exports, lib.BundleExport{
+			ExportDatabase: instance.ExportDatabase.Name,
+			ServingRequest: request,
+		})
+	}
+	return
+}
+
+// ImportBundle creates the export databases and exports of a bundle. Export databases are skipped if the user can
+// already access one with the same name, exports if the user already owns one with the same name. Exports are bound
+// to export databases by name.
+func (f *Serving) ImportBundle(bundle lib.Bundle, userId string, token string) (report lib.BundleReport, err error) {
+	databases, errs := f.GetExportDatabases(userId, nil, token)
+	if len(errs) > 0 {
+		return report, errors.Join(errs...)
+	}
+	instances, _, errs := f.GetInstancesForUser(userId, map[string][]string{}, token)
+	if len(errs) > 0 {
+		return report, errors.Join(errs...)
+	}
+	databaseIds := map[string]string{}
+	for _, database := range databases {
+		//prefer own export databases over public ones with the same name
+		if _, ok := databaseIds[database.Name]; !ok || database.UserId == userId {
+			databaseIds[database.Name] = database.ID
+		}
+	}
+	instanceNames := map[string]bool{}
+	for _, instance := range instances {
+		if instance.UserId == userId {
+			instanceNames[instance.Name] = true
+		}
+	}
+	report = lib.BundleReport{ExportDatabases: []lib.BundleReportItem{}, Exports: []lib.BundleReportItem{}}
+	credentialsRequired := map[string]bool{}
+	for _, name := range bundle.CredentialsRequired {
+		credentialsRequired[name] = true
+	}
+	for _, request := range bundle.ExportDatabases {
+		item := lib.BundleReportItem{Name: request.Name}
+		if id, ok := databaseIds[request.Name]; ok {
+			item.Status = lib.BulkStatusSkipped
+			item.ID = id
+			item.Reason = "export database with this name exists"
+		} else if database, errs := f.CreateExportDatabase("", request, userId); len(errs) > 0 {
+			item.Status = lib.BulkStatusFailed
+			item.Reason = errors.Join(errs...).Error()
+		} else {
+			item.Status = lib.BulkStatusCreated
+			item.ID = database.ID
+			databaseIds[database.Name] = database.ID
+			if credentialsRequired[request.Name] && (request.Credentials == nil || request.Credentials.IsEmpty()) {
+				item.Reason = "credentials are not part of the bundle and have to be set"
+			}
+		}
+		report.ExportDatabases = append(report.ExportDatabases, item)
+	}
+	for _, export := range bundle.Exports {
+		item := lib.BundleReportItem{Name: export.Name}
+		id, ok := databaseIds[export.ExportDatabase]
+		if instanceNames[export.Name] {
+			item.Status = lib.BulkStatusSkipped
+			item.Reason = "export with this name exists"
+		} else if !ok {
+			item.Status = lib.BulkStatusFailed
+			item.Reason = "unknown export database '" + export.ExportDatabase + "'"
+		} else {
+			request := export.ServingRequest
+			request.ExportDatabaseID = id
+			instance, err := f.CreateInstance(request, userId, token)
+			if err == nil && instance.ID == uuid.Nil {
+				err = errors.New("serving - unsupported filter type '" + request.FilterType + "'")
+			}
+			if err != nil {
+				item.Status = lib.BulkStatusFailed
+				item.Reason = err.Error()
+			} else {
+				item.Status = lib.BulkStatusCreated
+				item.ID = instance.ID.String()
+				instanceNames[export.Name] = true
+			}
+		}
+		report.Exports = append(report.Exports, item)
+	}
+	return
+}
@@ -19,6 +19,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"slices"
 	"time"
 
@@ -30,83 +31,129 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+// permissionsTopicSync describes a permissions-v2 topic whose resources are rows of model. addMissing adds the
+// permissions of a row that is unknown to permissions-v2.
+type permissionsTopicSync struct {
+	topic      string
+	model      interface{}
+	addMissing func(id string) error
+}
+
+// ExportInstanceCleanup reconciles the export-instances and export-databases permissions topics with the local db.
 func (f *Serving) ExportInstanceCleanup(recheckWait time.Duration) error {
 	if f.permissionsV2 != nil {
 		util.Logger.Info("start exporting instance permissions cleanup")
-		missingInPerm, missingInDb, err := f.findInconsistentExportInstanceIds()
-		if err != nil {
-			return err
+		topics := []permissionsTopicSync{
+			{topic: ExportInstancePermissionsTopic, model: &lib.Instance{}, addMissing: f.addMissingInstancePermissions},
+			{topic: ExportDatabasePermissionsTopic, model: &lib.ExportDatabase{}, addMissing: f.addMissingExportDatabasePermissions},
+		}
+		missingInPerm := make([][]string, len(topics))
+		missingInDb := make([][]string, len(topics))
+		count := 0
+		for i, topic := range topics {
+			var err error
+			missingInPerm[i], missingInDb[i], err = f.findInconsistentIds(topic.topic, topic.model)
+			if err != nil {
+				return err
+			}
+			count += len(missingInPerm[i]) + len(missingInDb[i])
 		}
 
-		allIds := []string{}
-		allIds = append(allIds, missingInPerm...)
-		allIds = append(allIds, missingInDb...)
-
-		if len(missingInPerm) > 0 || len(missingInDb) > 0 {
-			util.Logger.Info(fmt.Sprintf("wait %v before rechecking and deleting of %v ids", recheckWait.String(), len(allIds)))
+		if count > 0 {
+			util.Logger.Info(fmt.Sprintf("wait %v before rechecking and deleting of %v ids", recheckWait.String(), count))
 			time.Sleep(recheckWait)
 		}
 
-		permIdsMap, err, _ := f.permissionsV2.CheckMultiplePermissions(permV2Client.InternalAdminToken, ExportInstancePermissionsTopic, allIds)
+		for i, topic := range topics {
+			err := f.repairInconsistentIds(topic, missingInPerm[i], missingInDb[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Serving) repairInconsistentIds(topic permissionsTopicSync, missingInPerm []string, missingInDb []string) error {
+	allIds := []string{}
+	allIds = append(allIds, missingInPerm...)
+	allIds = append(allIds, missingInDb...)
+	if len(allIds) == 0 {
+		return nil
+	}
+
+	permIdsMap, err, _ := f.permissionsV2.CheckMultiplePermissions(permV2Client.InternalAdminToken, topic.topic, allIds)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range missingInDb {
+		util.Logger.Info(fmt.Sprintf("rechecking missing in db %v", id), "topic", topic.topic)
+		consistent, err := f.checkPermConsistency(permIdsMap, id, topic.model)
 		if err != nil {
 			return err
 		}
-
-		for _, id := range missingInDb {
-			util.Logger.Info(fmt.Sprintf("rechecking missing in db %v", id))
-			consistent, err := f.checkPermConsistency(permIdsMap, id)
+		if !consistent {
+			util.Logger.Info(fmt.Sprintf("inconsistent resource found, remove %v from permissions", id), "topic", topic.topic)
+			err, _ = f.permissionsV2.RemoveResource(permV2Client.InternalAdminToken, topic.topic, id)
 			if err != nil {
 				return err
 			}
-			if !consistent {
-				util.Logger.Info(fmt.Sprintf("inconsistent export instance found, remove %v from permissions", id))
-				err, _ = f.permissionsV2.RemoveResource(permV2Client.InternalAdminToken, ExportInstancePermissionsTopic, id)
-				if err != nil {
-					return err
-				}
-			}
 		}
-		for _, id := range missingInPerm {
-			util.Logger.Info(fmt.Sprintf("rechecking missing in permissions %v", id))
-			consistent, err := f.checkPermConsistency(permIdsMap, id)
+	}
+	for _, id := range missingInPerm {
+		util.Logger.Info(fmt.Sprintf("rechecking missing in permissions %v", id), "topic", topic.topic)
+		consistent, err := f.checkPermConsistency(permIdsMap, id, topic.model)
+		if err != nil {
+			return err
+		}
+		if !consistent {
+			err = topic.addMissing(id)
 			if err != nil {
 				return err
 			}
-			if !consistent {
-				instance, err := f.getInstanceById(id)
-				if err != nil {
-					return err
-				}
-				if instance.UserId != "" {
-					util.Logger.Info(fmt.Sprintf("inconsistent export instance found, add %v with user=%v to permissions\n", id, instance.UserId))
-					_, err, _ = f.permissionsV2.SetPermission(
-						permV2Client.InternalAdminToken,
-						ExportInstancePermissionsTopic,
-						id,
-						permV2Client.ResourcePermissions{
-							UserPermissions:  map[string]permV2Client.PermissionsMap{instance.UserId: {Read: true, Write: true, Execute: true, Administrate: true}},
-							GroupPermissions: map[string]permV2Client.PermissionsMap{},
-							RolePermissions:  map[string]model.PermissionsMap{},
-						},
-					)
-					if err != nil {
-						return err
-					}
-				} else {
-					util.Logger.Info(fmt.Sprintf("inconsistent export instance without user found, remove %v from local db", id))
-					_, errs := f.DeleteInstanceWithPermHandling(id, "", true, permV2Client.InternalAdminToken)
-					err = errors.Join(errs...)
-					if err != nil {
-						return err
-					}
-				}
-			}
 		}
 	}
 	return nil
 }
 
-func (f *Serving) findInconsistentExportInstanceIds() (missingInPerm []string, missingInDb []string, err error) {
+func (f *Serving) addMissingInstancePermissions(id string) error {
+	instance, err := f.getInstanceById(id)
+	if err != nil {
+		return err
+	}
+	if instance.UserId != "" {
+		util.Logger.Info(fmt.Sprintf("inconsistent export instance found, add %v with user=%v to permissions\n", id, instance.UserId))
+		_, err, _ = f.permissionsV2.SetPermission(
+			permV2Client.InternalAdminToken,
+			ExportInstancePermissionsTopic,
+			id,
+			permV2Client.ResourcePermissions{
+				UserPermissions:  map[string]permV2Client.PermissionsMap{instance.UserId: {Read: true, Write: true, Execute: true, Administrate: true}},
+				GroupPermissions: map[string]permV2Client.PermissionsMap{},
+				RolePermissions:  map[string]model.PermissionsMap{},
+			},
+		)
+		return err
+	}
+	util.Logger.Info(fmt.Sprintf("inconsistent export instance without user found, remove %v from local db", id))
+	_, errs := f.DeleteInstanceWithPermHandling(id, "", true, permV2Client.InternalAdminToken)
+	return errors.Join(errs...)
+}
+
+// addMissingExportDatabasePermissions migrates the owner and public flag of an export database to permissions-v2.
+func (f *Serving) addMissingExportDatabasePermissions(id string) error {
+	var database lib.ExportDatabase
+	err := db.DB.Where("id = ?", id).First(&database).Error
+	if err != nil {
+		return err
+	}
+	util.Logger.Info(fmt.Sprintf("inconsistent export database found, add %v with user=%v public=%v to permissions", id, database.UserId, database.Public))
+	_, err, _ = f.permissionsV2.SetPermission(permV2Client.InternalAdminToken, ExportDatabasePermissionsTopic, id, exportDatabasePermissions(database))
+	return err
+}
+
+func (f *Serving) findInconsistentIds(topic string, resourceModel interface{}) (missingInPerm []string, missingInDb []string, err error) {
 	if f.permissionsV2 != nil {
 		done := false
 		option := permV2Client.ListOptions{Limit: 100}
@@ -119,7 +166,7 @@ func (f *Serving) findInconsistentExportInstanceIds() (missingInPerm []string, m
 				defer f.permMux.Unlock()
 
 				//find ids in permissions
-				ids, err, _ := f.permissionsV2.AdminListResourceIds(permV2Client.InternalAdminToken, ExportInstancePermissionsTopic, option)
+				ids, err, _ := f.permissionsV2.AdminListResourceIds(permV2Client.InternalAdminToken, topic, option)
 				if err != nil {
 					return true, err
 				}
@@ -130,7 +177,7 @@ func (f *Serving) findInconsistentExportInstanceIds() (missingInPerm []string, m
 
 				//check if permission ids are in local db
 				if len(ids) > 0 {
-					rows, err := db.DB.Model(&lib.Instance{}).Select("id").Where("id IN (?)", ids).Rows()
+					rows, err := db.DB.Model(resourceModel).Select("id").Where("id IN (?)", ids).Rows()
 					if err != nil {
 						return true, err
 					}
@@ -170,7 +217,7 @@ func (f *Serving) findInconsistentExportInstanceIds() (missingInPerm []string, m
 			done, err = func() (bool, error) {
 				f.permMux.Lock()
 				defer f.permMux.Unlock()
-				rows, err := db.DB.Model(&lib.Instance{}).Select("id").Limit(option.Limit).Offset(option.Offset).Rows()
+				rows, err := db.DB.Model(resourceModel).Select("id").Limit(option.Limit).Offset(option.Offset).Rows()
 				if err != nil {
 					return true, err
 				}
@@ -206,10 +253,10 @@ func (f *Serving) findInconsistentExportInstanceIds() (missingInPerm []string, m
 	return missingInPerm, missingInDb, nil
 }
 
-func (f *Serving) checkPermConsistency(permIdsMap map[string]bool, id string) (consistent bool, err error) {
+func (f *Serving) checkPermConsistency(permIdsMap map[string]bool, id string, resourceModel interface{}) (consistent bool, err error) {
 	_, existsInPerm := permIdsMap[id]
 	var existsInDb bool
-	err = db.DB.Where("id = ?", id).First(&lib.Instance{}).Error
+	err = db.DB.Where("id = ?", id).First(reflect.New(reflect.TypeOf(resourceModel).Elem()).Interface()).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		existsInDb = false
 		return existsInPerm == existsInDb, nil
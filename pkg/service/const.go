@@ -16,7 +16,52 @@
 
 package service
 
+import (
+	"errors"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+)
+
 const (
 	PermV2DeviceTopic              = "devices"
 	ExportInstancePermissionsTopic = "export-instances"
+	ExportDatabasePermissionsTopic = "export-databases"
+	// PermV2PublicRole is granted read access on public export databases.
+	PermV2PublicRole = "user"
+	// DeploymentInternal marks export databases on the platform servers, only these fall back to the configured
+	// default connection.
+	DeploymentInternal = "internal"
+	// stoppedInstanceSweepInterval is how often stored data of deleted or truncated exports is removed once their
+	// filter removal is published.
+	stoppedInstanceSweepInterval = 5 * time.Second
+)
+
+var (
+	ErrUnsupportedDatabaseType = errors.New("unsupported export-database type")
+	ErrInvalidExport           = errors.New("invalid export")
+	ErrInvalidState            = errors.New("invalid export state")
+	ErrJobsDisabled            = errors.New("asynchronous jobs are disabled")
+	ErrPreviewUnsupported      = errors.New("driver does not support previews")
+	ErrMappingTestUnsupported  = errors.New("driver does not support mapping tests")
+	ErrCredentialsKeyMissing   = db.ErrCredentialsKeyMissing
 )
+
+// DependentExportsError lists the exports that prevent the removal of an export database. Exports the user can not
+// read are only counted.
+type DependentExportsError struct {
+	Message string                `json:"error"`
+	Exports []lib.DependentExport `json:"Exports"`
+	Hidden  int                   `json:"Hidden,omitempty"`
+	// Results reports the outcome per export if only some dependent exports could be deleted or moved.
+	Results []lib.BulkResult `json:"Results,omitempty"`
+}
+
+func (e *DependentExportsError) Error() string {
+	return e.Message
+}
+
+func (e *DependentExportsError) Unwrap() error {
+	return ErrInvalidState
+}
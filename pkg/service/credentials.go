@@ -0,0 +1,41 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"errors"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+)
+
+// withCredentials returns the export database with its decrypted credentials. Credentials are never nil afterwards
+// unless an error is returned.
+func withCredentials(database lib.ExportDatabase) (lib.ExportDatabase, error) {
+	if database.Credentials != nil {
+		return database, nil
+	}
+	if database.ID != "" {
+		if err := db.LoadCredentials(&database); err != nil {
+			return database, errors.New("could not load credentials of export-database " + database.ID + " - " + err.Error())
+		}
+	}
+	if database.Credentials == nil {
+		database.Credentials = &lib.Credentials{}
+	}
+	return database, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	permV2Client "github.com/SENERGY-Platform/permissions-v2/pkg/client"
+)
+
+// ReapExpiredInstances deletes exports whose expiry passed. Owners are warned once before an export expires if a
+// notification service is configured.
+func (f *Serving) ReapExpiredInstances() error {
+	var errs []error
+	now := time.Now()
+	if f.notifier != nil && f.expiryWarning > 0 {
+		var expiring lib.Instances
+		err := db.DB.Where("expires_at > ? AND expires_at < ? AND expiry_notified_at IS NULL AND state <> ?", now, now.Add(f.expiryWarning), lib.InstanceStateTrashed).Find(&expiring).Error
+		if err != nil {
+			return err
+		}
+		for _, instance := range expiring {
+			err = f.notifier.SendNotification(instance.UserId, "Export expires soon",
+				"The export '"+instance.Name+"' ("+instance.ID.String()+") expires at "+instance.ExpiresAt.UTC().Format(time.RFC3339)+" and will be deleted.")
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			err = db.DB.Model(&instance).UpdateColumn("expiry_notified_at", now).Error
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	var expired lib.Instances
+	err := db.DB.Select("id").Where("expires_at <= ? AND state NOT IN (?)", now, []string{lib.InstanceStateTrashed, lib.InstanceStateDeleting}).Find(&expired).Error
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, instance := range expired {
+		id := instance.ID.String()
+		_, e := f.DeleteInstanceWithPermHandling(id, "", true, permV2Client.InternalAdminToken)
+		if len(e) > 0 {
+			errs = append(errs, e...)
+			continue
+		}
+		util.Logger.Info("deleted expired export", "id", id)
+	}
+	return errors.Join(errs...)
+}
+
+func timeEqual(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
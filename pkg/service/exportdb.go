@@ -18,18 +18,23 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/SENERGY-Platform/analytics-serving/lib"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	permV2Client "github.com/SENERGY-Platform/permissions-v2/pkg/client"
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 )
 
-func (f *Serving) GetExportDatabases(userId string, args map[string][]string) (databases []lib.ExportDatabase, errs []error) {
-	DB := db.DB
-	tx := DB.Select("*").Where("public = TRUE OR user_id = ?", userId)
+func (f *Serving) GetExportDatabases(userId string, args map[string][]string, token string) (databases []lib.ExportDatabase, errs []error) {
+	tx, err := f.readableExportDatabases(userId, token)
+	if err != nil {
+		return nil, []error{err}
+	}
 	for arg, value := range args {
 		if arg == "limit" {
 			tx = tx.Limit(value[0])
@@ -83,8 +88,15 @@ func (f *Serving) GetExportDatabases(userId string, args map[string][]string) (d
 	return
 }
 
-func (f *Serving) GetExportDatabase(id string, userId string) (database lib.ExportDatabase, errs []error) {
-	errs = db.DB.Where("id = ? AND (user_id = ? OR public = TRUE)", id, userId).First(&database).GetErrors()
+func (f *Serving) GetExportDatabase(id string, userId string, token string) (database lib.ExportDatabase, errs []error) {
+	query := db.DB.Where("id = ? AND (user_id = ? OR public = TRUE)", id, userId)
+	if f.permissionsV2 != nil {
+		if err := f.checkExportDatabasePermission(token, id, permV2Client.Read); err != nil {
+			return database, []error{err}
+		}
+		query = db.DB.Where("id = ?", id)
+	}
+	errs = query.First(&database).GetErrors()
 	if len(errs) > 0 {
 		util.Logger.Error("retrieving export-database failed", "error", errs, "id", id)
 		return
@@ -100,6 +112,18 @@ func (f *Serving) CreateExportDatabase(id string, req lib.ExportDatabaseRequest,
 		}
 	}
 	database = populateExportDatabase(id, req, userId)
+	credentials, err := requestCredentials(&database, req)
+	if err != nil {
+		errs = append(errs, err)
+		return
+	}
+	if validator, ok := f.driver.(ExportDatabaseTypeValidator); ok {
+		err := validator.ValidateExportDatabaseType(database.Type)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+	}
 	if driver, ok := f.driver.(ExportWorkerKafkaApi); ok {
 		err := driver.CreateFilterTopic(database.EwFilterTopic, true)
 		if err != nil {
@@ -107,18 +131,48 @@ func (f *Serving) CreateExportDatabase(id string, req lib.ExportDatabaseRequest,
 			return
 		}
 	}
+	if f.permissionsV2 != nil {
+		f.permMux.RLock()
+		defer f.permMux.RUnlock()
+		_, err, _ = f.permissionsV2.SetPermission(permV2Client.InternalAdminToken, ExportDatabasePermissionsTopic, id, exportDatabasePermissions(database))
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+	}
 	db.DB.NewRecord(database)
-	errs = db.DB.Create(&database).GetErrors()
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if e := tx.Create(&database).Error; e != nil {
+			return e
+		}
+		if credentials == nil {
+			return nil
+		}
+		return db.SaveCredentials(tx, database.ID, *credentials)
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
 	if len(errs) > 0 {
 		util.Logger.Error("creating export-database failed", "error", errs)
+		if f.permissionsV2 != nil {
+			temperr, _ := f.permissionsV2.RemoveResource(permV2Client.InternalAdminToken, ExportDatabasePermissionsTopic, id)
+			util.Logger.Error("remove inconsistent permission", "error", err, "temperr", temperr)
+		}
 		return
 	}
 	util.Logger.Debug("successfully created export-database - " + database.ID)
+	check := f.checkExportDatabase(database)
+	database.Check = &check
 	return
 }
 
-func (f *Serving) UpdateExportDatabase(id string, req lib.ExportDatabaseRequest, userId string) (database lib.ExportDatabase, errs []error) {
-	errs = db.DB.Where("id = ? AND user_id = ?", id, userId).First(&database).GetErrors()
+func (f *Serving) UpdateExportDatabase(id string, req lib.ExportDatabaseRequest, userId string, token string) (database lib.ExportDatabase, errs []error) {
+	query := db.DB.Where("id = ? AND user_id = ?", id, userId)
+	if f.permissionsV2 != nil {
+		query = db.DB.Where("id = ?", id)
+	}
+	errs = query.First(&database).GetErrors()
 	if len(errs) > 0 {
 		for _, err := range errs {
 			if gorm.IsRecordNotFoundError(err) {
@@ -129,36 +183,432 @@ func (f *Serving) UpdateExportDatabase(id string, req lib.ExportDatabaseRequest,
 		util.Logger.Error("updating export-database failed", "error", errs, "id", id)
 		return
 	}
+	publicChanged := database.Public != req.Public
+	if f.permissionsV2 != nil {
+		permission := permV2Client.Write
+		if publicChanged {
+			permission = permV2Client.Administrate
+		}
+		if err := f.checkExportDatabasePermission(token, id, permission); err != nil {
+			return database, []error{err}
+		}
+		//shared export databases keep their owner
+		userId = database.UserId
+	}
 	dbType := database.Type
 	dbEwFilterTopic := database.EwFilterTopic
+	dbBucket := database.Bucket
 	database = populateExportDatabase(id, req, userId)
-	if database.Type != dbType || database.EwFilterTopic != dbEwFilterTopic {
-		errs = append(errs, errors.New("changing 'Type' or 'EwFilterTopic' not allowed"))
+	credentials, err := requestCredentials(&database, req)
+	if err != nil {
+		errs = append(errs, err)
+	} else if database.Type != dbType || database.EwFilterTopic != dbEwFilterTopic || database.Bucket != dbBucket {
+		errs = append(errs, errors.New("changing 'Type', 'EwFilterTopic' or 'Bucket' not allowed"))
 	} else {
-		errs = db.DB.Save(&database).GetErrors()
+		if credentials != nil && req.Credentials == nil {
+			//url credentials alone only replace the matching stored fields
+			stored := database
+			if err = db.LoadCredentials(&stored); err != nil {
+				errs = append(errs, err)
+				return
+			}
+			stored.Credentials.Merge(*credentials)
+			credentials = stored.Credentials
+		}
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			if credentials != nil {
+				if e := db.SaveCredentials(tx, database.ID, *credentials); e != nil {
+					return e
+				}
+			}
+			if e := tx.Save(&database).Error; e != nil {
+				return e
+			}
+			if publicChanged && f.permissionsV2 != nil {
+				return f.setExportDatabasePublic(id, database.Public)
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
 	if len(errs) > 0 {
 		util.Logger.Error("updating export-database failed", "error", errs, "id", id)
 		return
 	}
 	util.Logger.Debug("successfully updated export-database - " + database.ID)
+	check := f.checkExportDatabase(database)
+	database.Check = &check
 	return
 }
 
-func (f *Serving) DeleteExportDatabase(id string, userId string) (errs []error) {
+// DeleteExportDatabase removes an export database. Dependent exports are deleted with cascade or moved to the export
+// database moveTo, otherwise a DependentExportsError is returned. Deleted exports that are in the trash or whose stored
+// data is not removed yet keep the export database until SweepStoppedInstances finds it unreferenced.
+func (f *Serving) DeleteExportDatabase(id string, userId string, token string, cascade bool, moveTo string) (errs []error) {
 	var database lib.ExportDatabase
-	errs = db.DB.Where("id = ? AND user_id = ?", id, userId).First(&database).GetErrors()
+	query := db.DB.Where("id = ? AND user_id = ?", id, userId)
+	if f.permissionsV2 != nil {
+		if err := f.checkExportDatabasePermission(token, id, permV2Client.Administrate); err != nil {
+			return []error{err}
+		}
+		query = db.DB.Where("id = ?", id)
+	}
+	errs = query.First(&database).GetErrors()
 	if len(errs) > 0 {
 		util.Logger.Error("deleting export-database failed", "error", errs, "id", id)
 		return
 	}
-	errs = db.DB.Delete(&database).GetErrors()
-	if len(errs) > 0 {
+	err := f.handleDependentExports(database, userId, token, cascade, moveTo)
+	if err != nil {
+		return []error{err}
+	}
+	if f.permissionsV2 != nil {
+		f.permMux.RLock()
+		defer f.permMux.RUnlock()
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		//exports in the trash or with stored data still to be removed keep the export-database until they are gone
+		var dependents int
+		if e := tx.Model(&lib.Instance{}).Where("export_database_id = ?", database.ID).Count(&dependents).Error; e != nil {
+			return e
+		}
+		if dependents > 0 {
+			return tx.Delete(&database).Error
+		}
+		return removeExportDatabase(tx, database)
+	})
+	if err != nil {
+		errs = append(errs, err)
 		util.Logger.Error("deleting export-database failed", "error", errs, "id", id)
+		return
+	}
+	if f.permissionsV2 != nil {
+		err, _ = f.permissionsV2.RemoveResource(permV2Client.InternalAdminToken, ExportDatabasePermissionsTopic, id)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+// handleDependentExports deletes or moves all exports of an export database. The user needs administrate access to
+// delete and write access to move every dependent export. All dependent exports are checked before any is changed,
+// exports that fail afterwards are reported per export and keep the export database in use.
+func (f *Serving) handleDependentExports(database lib.ExportDatabase, userId string, token string, cascade bool, moveTo string) error {
+	var dependents lib.Instances
+	err := db.DB.Select("id, name, user_id, state").Where("export_database_id = ?", database.ID).Find(&dependents).Error
+	if err != nil {
+		return err
+	}
+	if len(dependents) == 0 {
+		return nil
+	}
+	if !cascade && moveTo == "" {
+		return f.dependentExportsError("export-database is used by exports", dependents, userId, token)
+	}
+	permission := permV2Client.Write
+	if cascade {
+		permission = permV2Client.Administrate
+	}
+	denied, err := f.inaccessibleInstances(dependents, userId, token, permission)
+	if err != nil {
+		return err
+	}
+	if len(denied) > 0 {
+		return f.dependentExportsError("export-database is used by exports of other users", denied, userId, token)
+	}
+	var target lib.ExportDatabase
+	if !cascade {
+		target, err = f.getMoveTarget(database, moveTo, userId, token)
+		if err != nil {
+			return err
+		}
+		var deleting lib.Instances
+		for _, dependent := range dependents {
+			if dependent.State == lib.InstanceStateDeleting {
+				deleting = append(deleting, dependent)
+			}
+		}
+		if len(deleting) > 0 {
+			return f.dependentExportsError("export-database is used by exports that are being deleted", deleting, userId, token)
+		}
+	}
+	var failed lib.Instances
+	results := make([]lib.BulkResult, len(dependents))
+	for i, dependent := range dependents {
+		id := dependent.ID.String()
+		results[i] = lib.BulkResult{Index: i, ID: id}
+		if cascade {
+			_, errs := f.DeleteInstanceWithPermHandling(id, "", true, token)
+			err = errors.Join(errs...)
+			results[i].Status = lib.BulkStatusDeleted
+		} else {
+			_, err = f.moveInstance(id, target, userId, token)
+			results[i].Status = lib.BulkStatusMoved
+		}
+		if err != nil {
+			util.Logger.Error("could not handle export of removed export-database", "error", err, "id", id)
+			results[i].Status = lib.BulkStatusFailed
+			results[i].Error = err.Error()
+			failed = append(failed, dependent)
+			continue
+		}
+		util.Logger.Debug("serving - "+results[i].Status+" export of removed export-database - "+id, "target", target.ID)
+	}
+	if len(failed) > 0 {
+		return &DependentExportsError{Message: "not all exports of the export-database could be handled", Exports: dependentExports(failed), Results: results}
+	}
+	return nil
+}
+
+// removeExportDatabase deletes an export database that is no longer referenced by any export.
+func removeExportDatabase(tx *gorm.DB, database lib.ExportDatabase) error {
+	if err := db.DeleteCredentials(tx, database.ID); err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&database).Error
+}
+
+// dependentExportsError lists the given exports, exports the user can not read are only counted.
+func (f *Serving) dependentExportsError(message string, instances lib.Instances, userId string, token string) error {
+	hidden, err := f.inaccessibleInstances(instances, userId, token, permV2Client.Read)
+	if err != nil {
+		return err
+	}
+	isHidden := map[uuid.UUID]bool{}
+	for _, instance := range hidden {
+		isHidden[instance.ID] = true
+	}
+	var readable lib.Instances
+	for _, instance := range instances {
+		if !isHidden[instance.ID] {
+			readable = append(readable, instance)
+		}
+	}
+	return &DependentExportsError{Message: message, Exports: dependentExports(readable), Hidden: len(hidden)}
+}
+
+// moveInstance assigns an export to another export database through the regular update path. Trashed exports have no
+// filter and only reference the new export database.
+func (f *Serving) moveInstance(id string, target lib.ExportDatabase, userId string, token string) (instance lib.Instance, err error) {
+	instance, err = f.getInstanceById(id)
+	if err != nil {
+		return
+	}
+	if instance.State == lib.InstanceStateTrashed {
+		instance.ExportDatabaseID = target.ID
+		instance.ExportDatabase = target
+		err = db.DB.Model(&lib.Instance{}).Where("id = ?", id).UpdateColumn("export_database_id", target.ID).Error
+		return
+	}
+	request := servingRequestFromInstance(instance)
+	request.ExportDatabaseID = target.ID
+	instance, errs := f.updateInstance(instance, userId, request, token)
+	return instance, errors.Join(errs...)
+}
+
+// inaccessibleInstances returns the exports the user lacks the permission for.
+func (f *Serving) inaccessibleInstances(instances lib.Instances, userId string, token string, permission permV2Client.Permission) (denied lib.Instances, err error) {
+	if f.permissionsV2 == nil {
+		for _, instance := range instances {
+			if instance.UserId != userId {
+				denied = append(denied, instance)
+			}
+		}
+		return
+	}
+	var ids []string
+	for _, instance := range instances {
+		ids = append(ids, instance.ID.String())
+	}
+	access, err, _ := f.permissionsV2.CheckMultiplePermissions(token, ExportInstancePermissionsTopic, ids, permission)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		if !access[instance.ID.String()] {
+			denied = append(denied, instance)
+		}
+	}
+	return
+}
+
+// getMoveTarget returns the export database exports of source can be moved to.
+func (f *Serving) getMoveTarget(source lib.ExportDatabase, targetId string, userId string, token string) (target lib.ExportDatabase, err error) {
+	if targetId == source.ID {
+		return target, fmt.Errorf("%w: source and target export-database are the same", ErrInvalidExport)
+	}
+	target, errs := f.GetExportDatabase(targetId, userId, token)
+	if len(errs) > 0 {
+		return target, fmt.Errorf("%w: export-database '%s' does not exist or user unauthorized", ErrInvalidExport, targetId)
+	}
+	if !compatibleExportDatabases(source, target) {
+		return target, fmt.Errorf("%w: export-database '%s' of type '%s' is not compatible with type '%s'", ErrInvalidExport, targetId, target.Type, source.Type)
+	}
+	return
+}
+
+func compatibleExportDatabases(a lib.ExportDatabase, b lib.ExportDatabase) bool {
+	return a.Type == b.Type
+}
+
+func dependentExports(instances lib.Instances) (exports []lib.DependentExport) {
+	exports = []lib.DependentExport{}
+	for _, instance := range instances {
+		exports = append(exports, lib.DependentExport{
+			ID:     instance.ID.String(),
+			Name:   instance.Name,
+			UserId: instance.UserId,
+			State:  instance.State,
+		})
+	}
+	return
+}
+
+// readableExportDatabases scopes a query to the export databases the user may read.
+func (f *Serving) readableExportDatabases(userId string, token string) (*gorm.DB, error) {
+	if f.permissionsV2 == nil {
+		return db.DB.Model(&lib.ExportDatabase{}).Where("public = TRUE OR user_id = ?", userId), nil
+	}
+	ids, err, _ := f.permissionsV2.ListAccessibleResourceIds(token, ExportDatabasePermissionsTopic, permV2Client.ListOptions{}, permV2Client.Read)
+	if err != nil {
+		return nil, err
+	}
+	return db.DB.Model(&lib.ExportDatabase{}).Where("id IN (?)", ids), nil
+}
+
+func (f *Serving) checkExportDatabasePermission(token string, id string, permission permV2Client.Permission) error {
+	access, err, _ := f.permissionsV2.CheckPermission(token, ExportDatabasePermissionsTopic, id, permission)
+	if err != nil {
+		return err
+	}
+	if !access {
+		return fmt.Errorf("access denied")
+	}
+	return nil
+}
+
+// setExportDatabasePublic grants or revokes read access of all platform users.
+func (f *Serving) setExportDatabasePublic(id string, public bool) error {
+	resource, err, _ := f.permissionsV2.GetResource(permV2Client.InternalAdminToken, ExportDatabasePermissionsTopic, id)
+	if err != nil {
+		return err
+	}
+	permissions := resource.ResourcePermissions
+	if permissions.RolePermissions == nil {
+		permissions.RolePermissions = map[string]permV2Client.PermissionsMap{}
+	}
+	if public {
+		permissions.RolePermissions[PermV2PublicRole] = permV2Client.PermissionsMap{Read: true}
+	} else {
+		delete(permissions.RolePermissions, PermV2PublicRole)
+	}
+	_, err, _ = f.permissionsV2.SetPermission(permV2Client.InternalAdminToken, ExportDatabasePermissionsTopic, id, permissions)
+	return err
+}
+
+// exportDatabasePermissions are the initial permissions of an export database. Public export databases are readable
+// by all platform users.
+func exportDatabasePermissions(database lib.ExportDatabase) permV2Client.ResourcePermissions {
+	permissions := permV2Client.ResourcePermissions{
+		UserPermissions:  map[string]permV2Client.PermissionsMap{},
+		GroupPermissions: map[string]permV2Client.PermissionsMap{},
+		RolePermissions:  map[string]permV2Client.PermissionsMap{},
+	}
+	if database.UserId != "" {
+		permissions.UserPermissions[database.UserId] = permV2Client.PermissionsMap{Read: true, Write: true, Execute: true, Administrate: true}
+	}
+	if database.Public {
+		permissions.RolePermissions[PermV2PublicRole] = permV2Client.PermissionsMap{Read: true}
+	}
+	return permissions
+}
+
+// CheckExportDatabase connects to the target of a readable export database and verifies its filter topic exists.
+func (f *Serving) CheckExportDatabase(id string, userId string, token string) (check lib.ExportDatabaseCheck, errs []error) {
+	database, errs := f.GetExportDatabase(id, userId, token)
+	if len(errs) > 0 {
+		return
+	}
+	return f.checkExportDatabase(database), nil
+}
+
+func (f *Serving) checkExportDatabase(database lib.ExportDatabase) (check lib.ExportDatabaseCheck) {
+	check = lib.ExportDatabaseCheck{OK: true, CheckedAt: time.Now()}
+	add := func(name string, skipped bool, err error) {
+		item := lib.ExportDatabaseCheckItem{Name: name, OK: err == nil && !skipped, Skipped: skipped}
+		if err != nil {
+			item.Error = err.Error()
+			check.OK = false
+		}
+		check.Checks = append(check.Checks, item)
+	}
+	switch database.Type {
+	case "influxdb", "influxdb2":
+		add(lib.ExportDatabaseCheckConnection, false, f.influx.Ping(database))
+	case "timescaledb", "postgres":
+		err := f.timescale.Ping(database)
+		add(lib.ExportDatabaseCheckConnection, false, err)
+		if database.Type == "timescaledb" {
+			if err != nil {
+				add(lib.ExportDatabaseCheckExtension, true, nil)
+			} else {
+				exists, err := f.timescale.HasExtension(database, "timescaledb")
+				if err == nil && !exists {
+					err = errors.New("extension 'timescaledb' is not installed")
+				}
+				add(lib.ExportDatabaseCheckExtension, false, err)
+			}
+		}
+	default:
+		add(lib.ExportDatabaseCheckConnection, true, nil)
+	}
+	if driver, ok := f.driver.(ExportWorkerKafkaApi); ok {
+		exists, err := driver.FilterTopicExists(database.EwFilterTopic)
+		if err == nil && !exists {
+			err = errors.New("filter topic '" + database.EwFilterTopic + "' does not exist")
+		}
+		add(lib.ExportDatabaseCheckFilterTopic, false, err)
+	} else {
+		add(lib.ExportDatabaseCheckFilterTopic, true, nil)
+	}
+	if !check.OK {
+		util.Logger.Warn("export-database check failed", "id", database.ID, "checks", check.Checks)
 	}
 	return
 }
 
+// requestCredentials collects the credentials of a request including those embedded in its url, which is stripped
+// from them. Nil is returned if the request contains no credentials and stored ones are kept. Without a credentials
+// key, url credentials stay in the url as before and credentials given separately are refused.
+func requestCredentials(database *lib.ExportDatabase, req lib.ExportDatabaseRequest) (*lib.Credentials, error) {
+	var credentials *lib.Credentials
+	if req.Credentials != nil {
+		c := *req.Credentials
+		credentials = &c
+	}
+	if db.CredentialsConfigured() {
+		var fromUrl lib.Credentials
+		database.Url, fromUrl = db.ExtractUrlCredentials(database.Url)
+		if !fromUrl.IsEmpty() {
+			if credentials == nil {
+				credentials = &lib.Credentials{}
+			}
+			credentials.Merge(fromUrl)
+		}
+	} else {
+		if _, fromUrl := db.ExtractUrlCredentials(database.Url); !fromUrl.IsEmpty() {
+			util.Logger.Warn("storing export-database url credentials in plain text, configure CREDENTIALS_KEY to encrypt them", "name", database.Name)
+		}
+		if credentials != nil && !credentials.IsEmpty() {
+			return nil, ErrCredentialsKeyMissing
+		}
+	}
+	return credentials, nil
+}
+
 func populateExportDatabase(id string, req lib.ExportDatabaseRequest, userId string) (database lib.ExportDatabase) {
 	database = lib.ExportDatabase{
 		ID:            id,
@@ -170,6 +620,8 @@ func populateExportDatabase(id string, req lib.ExportDatabaseRequest, userId str
 		EwFilterTopic: req.EwFilterTopic,
 		Public:        req.Public,
 		UserId:        userId,
+		Org:           req.Org,
+		Bucket:        req.Bucket,
 	}
 	return
 }
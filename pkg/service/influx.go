@@ -18,74 +18,161 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/SENERGY-Platform/analytics-serving/lib"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
 	influxClient "github.com/influxdata/influxdb1-client/v2"
 )
 
 type Influx interface {
 	ForceDeleteMeasurement(id string, userId string, instance lib.Instance) (errs []error)
+	Ping(database lib.ExportDatabase) error
+	CopyMeasurement(from lib.Instance, to lib.Instance, progress func(percent int)) error
 }
 
+// InfluxImpl keeps one client per influx server and credentials. Clients are created on first use
+// from the export database of an instance, the process-wide config only provides defaults.
 type InfluxImpl struct {
-	client influxClient.Client
+	defaults  config.InfluxConfig
+	clients   map[string]influxClient.Client
+	v2Clients map[string]influxdb2.Client
+	mux       sync.Mutex
 }
 
 func NewInflux(cfg config.InfluxConfig, ctx context.Context, wg *sync.WaitGroup) *InfluxImpl {
-	util.Logger.Info("init influx connection")
-	client, err := influxClient.NewHTTPClient(influxClient.HTTPConfig{
-		Addr:     cfg.Protocol + "://" + cfg.Host + ":" + strconv.Itoa(cfg.Port),
-		Username: cfg.User,
-		Password: cfg.Password,
-	})
-	if err != nil {
-		util.Logger.Error("could not connect to influx", "error", err)
+	util.Logger.Info("init influx connection pool")
+	impl := &InfluxImpl{
+		defaults:  cfg,
+		clients:   map[string]influxClient.Client{},
+		v2Clients: map[string]influxdb2.Client{},
 	}
+	wg.Add(1)
 	go func() {
-		wg.Add(1)
+		defer wg.Done()
 		<-ctx.Done()
-		if err = client.Close(); err != nil {
+		impl.close()
+	}()
+	return impl
+}
+
+func (i *InfluxImpl) close() {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	for key, client := range i.clients {
+		if err := client.Close(); err != nil {
 			util.Logger.Error("could not close influx connection", "error", err)
-		} else {
-			util.Logger.Info("closed influx connection")
 		}
-		wg.Done()
-	}()
-	return &InfluxImpl{client}
+		delete(i.clients, key)
+	}
+	for key, v2Client := range i.v2Clients {
+		v2Client.Close()
+		delete(i.v2Clients, key)
+	}
+	util.Logger.Info("closed influx connections")
+}
+
+// getClient returns the pooled client for the server of the given export database. Stored credentials
+// and credentials embedded in the url take precedence over the configured defaults. Only internal export
+// databases without a valid url use the configured server.
+func (i *InfluxImpl) getClient(database lib.ExportDatabase) (client influxClient.Client, err error) {
+	database, err = withCredentials(database)
+	if err != nil {
+		return nil, err
+	}
+	addr := i.defaults.Protocol + "://" + i.defaults.Host + ":" + strconv.Itoa(i.defaults.Port)
+	username := i.defaults.User
+	password := i.defaults.Password
+	u, err := parseInfluxUrl(database)
+	if err == nil {
+		if u.User != nil {
+			username = u.User.Username()
+			password, _ = u.User.Password()
+		}
+		addr = u.Scheme + "://" + u.Host
+		if database.Credentials.Username != "" {
+			username = database.Credentials.Username
+			password = database.Credentials.Password
+		}
+	} else if database.Deployment != DeploymentInternal {
+		return nil, err
+	}
+	key := addr + "|" + username + "|" + password
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	client, ok := i.clients[key]
+	if ok {
+		return client, nil
+	}
+	client, err = influxClient.NewHTTPClient(influxClient.HTTPConfig{
+		Addr:     addr,
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	util.Logger.Debug("created influx client", "addr", addr)
+	i.clients[key] = client
+	return client, nil
 }
 
+// measurementDropAttempts limits how often a measurement is dropped while points are still written to it.
+const measurementDropAttempts = 10
+
 func (i *InfluxImpl) ForceDeleteMeasurement(id string, userId string, instance lib.Instance) (errs []error) {
 	defer func() {
 		if err := recover(); err != nil {
 			errs = append(errs, errors.New("force delete influx measurement failed - panic occurred: "+fmt.Sprint(err)))
 		}
 	}()
-	for {
-		errs = i.dropMeasurement(instance)
+	if instance.ExportDatabase.Type == "influxdb2" {
+		if err := i.deleteMeasurementV2(instance); err != nil {
+			errs = append(errs, err)
+		}
+		return
+	}
+	client, err := i.getClient(instance.ExportDatabase)
+	if err != nil {
+		errs = append(errs, err)
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		errs = dropMeasurement(client, instance)
 		if len(errs) > 0 {
 			return
 		}
-		measurements, err := i.getMeasurements(userId)
+		measurements, err := getMeasurements(client, instance.Database)
 		if err != nil {
 			errs = append(errs, err)
 			return
 		}
-		if !util.StringInSlice(id, measurements) {
+		if !util.StringInSlice(instance.Measurement, measurements) {
 			break
 		}
+		if attempt >= measurementDropAttempts {
+			errs = append(errs, fmt.Errorf("measurement %s still exists after %d drop attempts", instance.Measurement, attempt))
+			return
+		}
+		time.Sleep(time.Second)
 	}
 	return
 }
 
-func (i *InfluxImpl) dropMeasurement(instance lib.Instance) (errors []error) {
+func dropMeasurement(client influxClient.Client, instance lib.Instance) (errors []error) {
 	q := influxClient.NewQuery("DROP MEASUREMENT "+"\""+instance.Measurement+"\"", instance.Database, "")
-	response, err := i.client.Query(q)
+	response, err := client.Query(q)
 	if err != nil {
 		errors = append(errors, err)
 	}
@@ -95,9 +182,9 @@ func (i *InfluxImpl) dropMeasurement(instance lib.Instance) (errors []error) {
 	return
 }
 
-func (i *InfluxImpl) getMeasurements(userId string) (measurements []string, err error) {
-	q := influxClient.NewQuery("SHOW MEASUREMENTS", userId, "")
-	response, err := i.client.Query(q)
+func getMeasurements(client influxClient.Client, database string) (measurements []string, err error) {
+	q := influxClient.NewQuery("SHOW MEASUREMENTS", database, "")
+	response, err := client.Query(q)
 	if err != nil {
 		return
 	}
@@ -113,3 +200,309 @@ func (i *InfluxImpl) getMeasurements(userId string) (measurements []string, err
 	}
 	return measurements, err
 }
+
+func (i *InfluxImpl) deleteMeasurementV2(instance lib.Instance) error {
+	ctx, cf := context.WithTimeout(context.Background(), time.Minute)
+	defer cf()
+	client, err := i.getV2Client(instance.ExportDatabase)
+	if err != nil {
+		return err
+	}
+	return client.DeleteAPI().DeleteWithName(ctx,
+		instance.ExportDatabase.Org,
+		instance.InfluxBucket(),
+		time.Unix(0, 0),
+		time.Now(),
+		"_measurement=\""+instance.Measurement+"\"",
+	)
+}
+
+func parseInfluxUrl(database lib.ExportDatabase) (*url.URL, error) {
+	u, err := url.Parse(database.Url)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("invalid url of influx export-database " + database.ID)
+	}
+	return u, nil
+}
+
+func (i *InfluxImpl) getV2Client(database lib.ExportDatabase) (influxdb2.Client, error) {
+	//there is no default influxdb2 server
+	if _, err := parseInfluxUrl(database); err != nil {
+		return nil, err
+	}
+	database, err := withCredentials(database)
+	if err != nil {
+		return nil, err
+	}
+	token := database.Credentials.Token
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	key := database.Url + "|" + token
+	v2Client, ok := i.v2Clients[key]
+	if !ok {
+		v2Client = influxdb2.NewClient(database.Url, token)
+		i.v2Clients[key] = v2Client
+	}
+	return v2Client, nil
+}
+
+// Ping checks that the server of an export database is reachable and accepts its credentials.
+func (i *InfluxImpl) Ping(database lib.ExportDatabase) (err error) {
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	if database.Type == "influxdb2" {
+		var client influxdb2.Client
+		client, err = i.getV2Client(database)
+		if err != nil {
+			return
+		}
+		if _, err = client.Ping(ctx); err != nil {
+			return
+		}
+		_, err = client.OrganizationsAPI().FindOrganizationByName(ctx, database.Org)
+		return
+	}
+	client, err := i.getClient(database)
+	if err != nil {
+		return
+	}
+	if _, _, err = client.Ping(10 * time.Second); err != nil {
+		return
+	}
+	response, err := client.Query(influxClient.NewQuery("SHOW DATABASES", "", ""))
+	if err != nil {
+		return
+	}
+	return response.Error()
+}
+
+const influxCopyBatchSize = 5000
+
+// CopyMeasurement writes all points of the measurement of from into the export database of to. Points with the same
+// time and tags as existing points overwrite them.
+func (i *InfluxImpl) CopyMeasurement(from lib.Instance, to lib.Instance, progress func(percent int)) (err error) {
+	if from.ExportDatabase.Type == "influxdb2" {
+		return i.copyMeasurementV2(from, to, progress)
+	}
+	src, err := i.getClient(from.ExportDatabase)
+	if err != nil {
+		return
+	}
+	dst, err := i.getClient(to.ExportDatabase)
+	if err != nil {
+		return
+	}
+	measurement := "\"" + from.Measurement + "\""
+	total, err := countPoints(src, from.Database, measurement)
+	if err != nil || total == 0 {
+		return
+	}
+	tagKeys, err := getTagKeys(src, from.Database, measurement)
+	if err != nil {
+		return
+	}
+	intFields := map[string]bool{}
+	for _, value := range from.Values {
+		if !value.Tag && value.Type == "int" {
+			intFields[value.Name] = true
+		}
+	}
+	response, err := src.QueryAsChunk(influxClient.Query{
+		Command:   "SELECT * FROM " + measurement,
+		Database:  from.Database,
+		Precision: "ns",
+		Chunked:   true,
+		ChunkSize: influxCopyBatchSize,
+	})
+	if err != nil {
+		return
+	}
+	defer response.Close()
+	copied := 0
+	for {
+		chunk, err := response.NextResponse()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if chunk.Error() != nil {
+			return chunk.Error()
+		}
+		batch, err := influxClient.NewBatchPoints(influxClient.BatchPointsConfig{Database: to.Database, Precision: "ns"})
+		if err != nil {
+			return err
+		}
+		for _, result := range chunk.Results {
+			for _, series := range result.Series {
+				for _, row := range series.Values {
+					point, err := rowToPoint(to.Measurement, series.Columns, row, tagKeys, intFields)
+					if err != nil {
+						return err
+					}
+					batch.AddPoint(point)
+				}
+			}
+		}
+		if err = dst.Write(batch); err != nil {
+			return errors.New("copying points of measurement " + measurement + " failed - " + err.Error())
+		}
+		copied += len(batch.Points())
+		progress(min(99, copied*100/total))
+	}
+}
+
+// countPoints returns the highest count of all fields of a measurement, which is the number of points unless fields
+// are sparse.
+func countPoints(client influxClient.Client, database string, measurement string) (total int, err error) {
+	response, err := client.Query(influxClient.NewQuery("SELECT COUNT(*) FROM "+measurement, database, ""))
+	if err != nil {
+		return
+	}
+	if err = response.Error(); err != nil {
+		return
+	}
+	if len(response.Results) == 0 || len(response.Results[0].Series) == 0 || len(response.Results[0].Series[0].Values) == 0 {
+		return
+	}
+	for _, value := range response.Results[0].Series[0].Values[0][1:] {
+		if number, ok := value.(json.Number); ok {
+			count, e := number.Int64()
+			if e == nil && int(count) > total {
+				total = int(count)
+			}
+		}
+	}
+	return
+}
+
+func getTagKeys(client influxClient.Client, database string, measurement string) (tagKeys map[string]bool, err error) {
+	response, err := client.Query(influxClient.NewQuery("SHOW TAG KEYS FROM "+measurement, database, ""))
+	if err != nil {
+		return
+	}
+	if err = response.Error(); err != nil {
+		return
+	}
+	tagKeys = map[string]bool{}
+	for _, result := range response.Results {
+		for _, series := range result.Series {
+			for _, value := range series.Values {
+				if key, ok := value[0].(string); ok {
+					tagKeys[key] = true
+				}
+			}
+		}
+	}
+	return
+}
+
+// rowToPoint converts a row of a query with nanosecond precision into a point. Numbers are written as float unless
+// the export defines the field as int, like the export worker does.
+func rowToPoint(measurement string, columns []string, row []interface{}, tagKeys map[string]bool, intFields map[string]bool) (*influxClient.Point, error) {
+	var timestamp time.Time
+	tags := map[string]string{}
+	fields := map[string]interface{}{}
+	for i, column := range columns {
+		value := row[i]
+		if value == nil {
+			continue
+		}
+		if column == "time" {
+			ns, err := value.(json.Number).Int64()
+			if err != nil {
+				return nil, err
+			}
+			timestamp = time.Unix(0, ns)
+			continue
+		}
+		if tagKeys[column] {
+			tags[column] = fmt.Sprint(value)
+			continue
+		}
+		if number, ok := value.(json.Number); ok {
+			var err error
+			if intFields[column] {
+				value, err = number.Int64()
+			} else {
+				value, err = number.Float64()
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		fields[column] = value
+	}
+	return influxClient.NewPoint(measurement, tags, fields, timestamp)
+}
+
+func (i *InfluxImpl) copyMeasurementV2(from lib.Instance, to lib.Instance, progress func(percent int)) (err error) {
+	ctx := context.Background()
+	query := fmt.Sprintf("from(bucket: %q) |> range(start: 0) |> filter(fn: (r) => r._measurement == %q)", from.InfluxBucket(), from.Measurement)
+	srcClient, err := i.getV2Client(from.ExportDatabase)
+	if err != nil {
+		return
+	}
+	dstClient, err := i.getV2Client(to.ExportDatabase)
+	if err != nil {
+		return
+	}
+	src := srcClient.QueryAPI(from.ExportDatabase.Org)
+	countResult, err := src.Query(ctx, query+" |> group() |> count()")
+	if err != nil {
+		return
+	}
+	total := 0
+	for countResult.Next() {
+		if count, ok := countResult.Record().Value().(int64); ok {
+			total += int(count)
+		}
+	}
+	if err = countResult.Err(); err != nil || total == 0 {
+		return
+	}
+	result, err := src.Query(ctx, query)
+	if err != nil {
+		return
+	}
+	defer result.Close()
+	dst := dstClient.WriteAPIBlocking(to.ExportDatabase.Org, to.InfluxBucket())
+	var batch []*write.Point
+	copied := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := dst.WritePoint(ctx, batch...); err != nil {
+			return errors.New("copying points of measurement " + from.Measurement + " failed - " + err.Error())
+		}
+		copied += len(batch)
+		batch = batch[:0]
+		progress(min(99, copied*100/total))
+		return nil
+	}
+	for result.Next() {
+		record := result.Record()
+		tags := map[string]string{}
+		for key, value := range record.Values() {
+			//columns starting with an underscore and result and table are flux metadata
+			if strings.HasPrefix(key, "_") || key == "result" || key == "table" {
+				continue
+			}
+			if tag, ok := value.(string); ok {
+				tags[key] = tag
+			}
+		}
+		batch = append(batch, write.NewPoint(to.Measurement, tags, map[string]interface{}{record.Field(): record.Value()}, record.Time()))
+		if len(batch) >= influxCopyBatchSize {
+			if err = flush(); err != nil {
+				return
+			}
+		}
+	}
+	if err = result.Err(); err != nil {
+		return
+	}
+	return flush()
+}
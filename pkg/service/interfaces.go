@@ -16,11 +16,24 @@
 
 package service
 
-import "github.com/SENERGY-Platform/analytics-serving/lib"
+import (
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/jinzhu/gorm"
+)
 
+// Driver applies export changes. Implementations may use tx to record their work in the same
+// transaction as the instance change.
 type Driver interface {
-	CreateInstance(instance *lib.Instance, dataFields string, tagFields string) (serviceId string, err error)
-	DeleteInstance(instance *lib.Instance) (err error)
+	CreateInstance(tx *gorm.DB, instance *lib.Instance, dataFields string, tagFields string) (serviceId string, err error)
+	DeleteInstance(tx *gorm.DB, instance *lib.Instance) (err error)
+}
+
+type DriverPreview interface {
+	PreviewInstance(instance *lib.Instance, dataFields string, tagFields string) (preview interface{}, err error)
+}
+
+type DriverMappingTest interface {
+	TestMapping(instance *lib.Instance, dataFields string, tagFields string, sample interface{}) (result lib.MappingTestResult, err error)
 }
 
 type PipelineApiService interface {
@@ -31,7 +44,16 @@ type ImportDeployService interface {
 	UserHasImportAccess(id string, authorization string) (bool, error)
 }
 
+type NotificationService interface {
+	SendNotification(userId string, title string, message string) error
+}
+
 type ExportWorkerKafkaApi interface {
 	CreateFilterTopic(topic string, checkExists bool) error
+	FilterTopicExists(topic string) (bool, error)
 	InitFilterTopics(serving *Serving) error
 }
+
+type ExportDatabaseTypeValidator interface {
+	ValidateExportDatabaseType(dbType string) error
+}
@@ -0,0 +1,333 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	permV2Client "github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"github.com/google/uuid"
+)
+
+// SubmitCreateJob checks access like CreateInstance and queues the creation of the export.
+func (f *Serving) SubmitCreateJob(req lib.ServingRequest, userId string, token string) (job lib.Job, err error) {
+	err = f.checkSourceAccess(req, token)
+	if err != nil {
+		return
+	}
+	if _, errs := f.GetExportDatabase(req.ExportDatabaseID, userId, token); len(errs) > 0 {
+		return job, errors.New("export-database does not exist or user unauthorized")
+	}
+	req.Offset, err = resolveOffset(req.Offset)
+	if err != nil {
+		return
+	}
+	return f.submitJob(lib.JobTypeCreate, uuid.NewString(), userId, req)
+}
+
+// SubmitUpdateJob checks access like UpdateInstance and queues the update of the export.
+func (f *Serving) SubmitUpdateJob(id string, userId string, req lib.ServingRequest, token string) (job lib.Job, err error) {
+	err = f.checkSourceAccess(req, token)
+	if err != nil {
+		return
+	}
+	instance, err := f.getWritableInstance(id, userId, token)
+	if err != nil {
+		return
+	}
+	if req.ExportDatabaseID != instance.ExportDatabaseID {
+		if _, errs := f.GetExportDatabase(req.ExportDatabaseID, userId, token); len(errs) > 0 {
+			return job, errors.New("export-database does not exist or user unauthorized")
+		}
+	}
+	req.Offset, err = resolveOffset(req.Offset)
+	if err != nil {
+		return
+	}
+	return f.submitJob(lib.JobTypeUpdate, id, userId, req)
+}
+
+// SubmitDeleteJob checks access like DeleteInstanceForUser and queues the deletion of the export.
+func (f *Serving) SubmitDeleteJob(id string, userId string, token string) (job lib.Job, err error) {
+	if f.permissionsV2 != nil {
+		access, err, _ := f.permissionsV2.CheckPermission(token, ExportInstancePermissionsTopic, id, permV2Client.Administrate)
+		if err != nil {
+			return job, err
+		}
+		if !access {
+			return job, fmt.Errorf("access denied")
+		}
+	}
+	instance := lib.Instance{}
+	query := db.DB.Where("id = ?", id)
+	if f.permissionsV2 == nil {
+		query = db.DB.Where("id = ? AND user_id = ?", id, userId)
+	}
+	err = errors.Join(query.First(&instance).GetErrors()...)
+	if err != nil {
+		return
+	}
+	return f.submitJob(lib.JobTypeDelete, id, userId, nil)
+}
+
+// moveJobRequest records the original export database, so an interrupted move job can still copy the data.
+type moveJobRequest struct {
+	lib.MoveRequest
+	SourceExportDatabaseID string
+}
+
+// SubmitMoveJob checks access like MoveInstance and queues the move of the export including a copy of its data.
+func (f *Serving) SubmitMoveJob(id string, req lib.MoveRequest, userId string, token string) (job lib.Job, err error) {
+	instance, _, err := f.checkMove(id, req, userId, token)
+	if err != nil {
+		return
+	}
+	return f.submitJob(lib.JobTypeMove, id, userId, moveJobRequest{MoveRequest: req, SourceExportDatabaseID: instance.ExportDatabaseID})
+}
+
+func (f *Serving) GetJob(id string, userId string) (job lib.Job, err error) {
+	err = errors.Join(db.DB.Where("id = ? AND user_id = ?", id, userId).First(&job).GetErrors()...)
+	return
+}
+
+func (f *Serving) submitJob(jobType string, instanceId string, userId string, req interface{}) (job lib.Job, err error) {
+	if f.jobNotify == nil {
+		return job, ErrJobsDisabled
+	}
+	job = lib.Job{
+		ID:         uuid.New(),
+		Type:       jobType,
+		State:      lib.JobStatePending,
+		UserId:     userId,
+		InstanceID: instanceId,
+	}
+	if req != nil {
+		b, err := json.Marshal(req)
+		if err != nil {
+			return job, err
+		}
+		job.Request = string(b)
+	}
+	err = errors.Join(db.DB.Create(&job).GetErrors()...)
+	if err != nil {
+		return
+	}
+	select {
+	case f.jobNotify <- struct{}{}:
+	default:
+	}
+	return
+}
+
+// startJobWorkers runs queued jobs until ctx is done. Jobs are leased to a worker, jobs of a stopped worker are
+// executed again once their lease expired.
+func (f *Serving) startJobWorkers(ctx context.Context, wg *sync.WaitGroup, cfg config.JobConfig) (err error) {
+	if cfg.Workers < 1 {
+		return
+	}
+	interval, err := time.ParseDuration(cfg.PollInterval)
+	if err != nil {
+		return
+	}
+	retention, err := time.ParseDuration(cfg.Retention)
+	if err != nil {
+		return
+	}
+	lease, err := time.ParseDuration(cfg.LeaseDuration)
+	if err != nil {
+		return
+	}
+	if lease <= 0 {
+		return errors.New("job lease duration must be positive")
+	}
+	f.jobNotify = make(chan struct{}, cfg.Workers)
+	workerId := uuid.NewString()
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.jobWorker(ctx, workerId, interval, lease)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			err := db.DeleteFinishedJobs(time.Now().Add(-retention))
+			if err != nil {
+				util.Logger.Error("could not remove finished jobs", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	util.Logger.Info("started job workers", "count", cfg.Workers, "worker_id", workerId)
+	return
+}
+
+func (f *Serving) jobWorker(ctx context.Context, workerId string, interval time.Duration, lease time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil && f.runNextJob(workerId, lease) {
+		}
+		select {
+		case <-ctx.Done():
+			util.Logger.Info("stopped job worker")
+			return
+		case <-f.jobNotify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNextJob claims and runs the oldest runnable job. The lease of the job is renewed while it runs.
+func (f *Serving) runNextJob(workerId string, lease time.Duration) bool {
+	job, err := db.ClaimNextJob(workerId, lease)
+	if err != nil {
+		util.Logger.Error("could not claim job", "error", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				leased, err := db.ExtendJobLease(job.ID, workerId, lease)
+				if err != nil {
+					util.Logger.Error("could not extend job lease", "error", err, "id", job.ID.String())
+				} else if !leased {
+					util.Logger.Warn("job lease lost", "id", job.ID.String())
+				}
+			}
+		}
+	}()
+	err = f.runJob(*job)
+	close(done)
+	if err != nil {
+		util.Logger.Error("job failed", "error", err, "id", job.ID.String(), "type", job.Type)
+	}
+	finished, err := db.FinishJob(job.ID, workerId, err)
+	if err != nil {
+		util.Logger.Error("could not persist job result", "error", err, "id", job.ID.String())
+	} else if !finished {
+		util.Logger.Warn("job lease lost, result discarded", "id", job.ID.String())
+	}
+	return true
+}
+
+func (f *Serving) runJob(job lib.Job) (err error) {
+	var req lib.ServingRequest
+	if job.Request != "" {
+		err = json.Unmarshal([]byte(job.Request), &req)
+		if err != nil {
+			return
+		}
+	}
+	switch job.Type {
+	case lib.JobTypeCreate:
+		id, err := uuid.Parse(job.InstanceID)
+		if err != nil {
+			return err
+		}
+		if _, err = f.getInstanceById(job.InstanceID); err == nil {
+			//created before the job was interrupted
+			return nil
+		}
+		_, err = f.createInstance(id, req, job.UserId, permV2Client.InternalAdminToken)
+		return err
+	case lib.JobTypeUpdate:
+		instance, err := f.getInstanceById(job.InstanceID)
+		if err != nil {
+			return err
+		}
+		_, errs := f.updateInstance(instance, job.UserId, req, permV2Client.InternalAdminToken)
+		return errors.Join(errs...)
+	case lib.JobTypeDelete:
+		//access has been checked on submit
+		_, errs := f.DeleteInstanceWithPermHandling(job.InstanceID, "", true, permV2Client.InternalAdminToken)
+		return errors.Join(errs...)
+	case lib.JobTypeMove:
+		return f.runMoveJob(job)
+	}
+	return errors.New("unknown job type '" + job.Type + "'")
+}
+
+// runMoveJob moves an export and copies its data from the original export database. Progress is reported while
+// copying. Jobs interrupted after the move only copy the data again.
+func (f *Serving) runMoveJob(job lib.Job) (err error) {
+	var req moveJobRequest
+	err = json.Unmarshal([]byte(job.Request), &req)
+	if err != nil {
+		return
+	}
+	instance, err := f.getInstanceById(job.InstanceID)
+	if err != nil {
+		return
+	}
+	var source lib.ExportDatabase
+	//the source may have been removed since, trashed exports of removed export databases can still be moved
+	err = db.DB.Unscoped().Where("id = ?", req.SourceExportDatabaseID).First(&source).Error
+	if err != nil {
+		return
+	}
+	if instance.ExportDatabaseID != req.ExportDatabaseID {
+		var target lib.ExportDatabase
+		err = db.DB.Where("id = ?", req.ExportDatabaseID).First(&target).Error
+		if err != nil {
+			return
+		}
+		//access has been checked on submit
+		instance, err = f.moveInstance(job.InstanceID, target, job.UserId, permV2Client.InternalAdminToken)
+		if err != nil {
+			return
+		}
+	}
+	if !req.CopyData {
+		return
+	}
+	from := instance
+	from.ExportDatabaseID = source.ID
+	from.ExportDatabase = source
+	return f.copyInstanceData(from, instance, func(percent int) {
+		err := db.DB.Model(&lib.Job{}).Where("id = ?", job.ID).UpdateColumn("progress", percent).Error
+		if err != nil {
+			util.Logger.Error("could not persist job progress", "error", err, "id", job.ID.String())
+		}
+	})
+}
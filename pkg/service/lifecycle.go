@@ -0,0 +1,443 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	permV2Client "github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+func (f *Serving) getWritableInstance(id string, userId string, token string) (instance lib.Instance, err error) {
+	query := db.DB.Where("id = ? AND user_id = ?", id, userId)
+	if f.permissionsV2 != nil {
+		access, err, _ := f.permissionsV2.CheckPermission(token, ExportInstancePermissionsTopic, id, permV2Client.Write)
+		if err != nil {
+			return instance, err
+		}
+		if !access {
+			return instance, fmt.Errorf("access denied")
+		}
+		query = db.DB.Where("id = ?", id)
+	}
+	err = errors.Join(query.Preload("Values").Preload("ExportDatabase", withRemoved).First(&instance).GetErrors()...)
+	return
+}
+
+// withRemoved includes export databases that are only kept for exports in the trash or being deleted.
+func withRemoved(tx *gorm.DB) *gorm.DB {
+	return tx.Unscoped()
+}
+
+// PauseInstance removes the filter of an export from the export worker. The export definition and
+// all stored data are kept.
+func (f *Serving) PauseInstance(id string, userId string, token string) (instance lib.Instance, err error) {
+	instance, err = f.getWritableInstance(id, userId, token)
+	if err != nil {
+		return
+	}
+	if instance.State == lib.InstanceStatePaused {
+		return
+	}
+	if instance.State == lib.InstanceStateDeleting || instance.State == lib.InstanceStateTrashed {
+		return instance, fmt.Errorf("%w: export is being deleted", ErrInvalidState)
+	}
+	if instance.State == lib.InstanceStateTruncating {
+		return instance, fmt.Errorf("%w: stored data is being truncated", ErrInvalidState)
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if e := f.driver.DeleteInstance(tx, &instance); e != nil {
+			return e
+		}
+		return setInstanceState(tx, &instance, lib.InstanceStatePaused, nil)
+	})
+	if err != nil {
+		return
+	}
+	util.Logger.Debug("serving - paused export - " + id)
+	return
+}
+
+// ResumeInstance republishes the filter of a paused or failed export.
+func (f *Serving) ResumeInstance(id string, userId string, token string) (instance lib.Instance, err error) {
+	instance, err = f.getWritableInstance(id, userId, token)
+	if err != nil {
+		return
+	}
+	if instance.State != lib.InstanceStatePaused && instance.State != lib.InstanceStateFailed {
+		return instance, fmt.Errorf("%w: only paused or failed exports can be resumed", ErrInvalidState)
+	}
+	//the export becomes active again once the outbox relay published its filter
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if e := f.CreateFromInstance(tx, &instance); e != nil {
+			return e
+		}
+		return setInstanceState(tx, &instance, lib.InstanceStatePending, nil)
+	})
+	if err != nil {
+		return
+	}
+	util.Logger.Debug("serving - resumed export - " + id)
+	return
+}
+
+// ReplayInstance restarts consumption of an export at the requested offset by rotating its application id and
+// republishing the filter. Stored data is removed first if requested, running exports are republished once it is
+// removed. Paused exports replay once resumed.
+func (f *Serving) ReplayInstance(id string, req lib.ReplayRequest, userId string, token string) (instance lib.Instance, err error) {
+	req.Offset, err = resolveOffset(req.Offset)
+	if err != nil {
+		return
+	}
+	instance, err = f.getWritableInstance(id, userId, token)
+	if err != nil {
+		return
+	}
+	if instance.State == lib.InstanceStateDeleting || instance.State == lib.InstanceStateTrashed {
+		return instance, fmt.Errorf("%w: export is being deleted", ErrInvalidState)
+	}
+	if instance.State == lib.InstanceStateTruncating {
+		return instance, fmt.Errorf("%w: stored data is being truncated", ErrInvalidState)
+	}
+	old := instance
+	paused := instance.State == lib.InstanceStatePaused
+	if req.TruncateData && paused {
+		err = errors.Join(f.truncateInstanceData(instance)...)
+		if err != nil {
+			return
+		}
+	}
+	instance.ApplicationId = uuid.New()
+	instance.Offset = req.Offset
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if !paused && req.TruncateData {
+			//the running consumer is stopped first, SweepStoppedInstances truncates and republishes the export
+			if e := f.driver.DeleteInstance(tx, &instance); e != nil {
+				return e
+			}
+			if e := setInstanceState(tx, &instance, lib.InstanceStateTruncating, nil); e != nil {
+				return e
+			}
+		} else if !paused {
+			if e := f.CreateFromInstance(tx, &instance); e != nil {
+				return e
+			}
+			if e := setInstanceState(tx, &instance, lib.InstanceStatePending, nil); e != nil {
+				return e
+			}
+		}
+		if e := addRevision(tx, instance.ID, userId, &old, servingRequestFromInstance(instance)); e != nil {
+			return e
+		}
+		return tx.Model(&lib.Instance{}).Where("id = ?", instance.ID).UpdateColumns(map[string]interface{}{
+			"application_id": instance.ApplicationId,
+			"offset":         instance.Offset,
+		}).Error
+	})
+	if err != nil {
+		return
+	}
+	util.Logger.Debug("serving - replaying export - "+id, "offset", req.Offset)
+	return
+}
+
+// resolveOffset resolves a requested start offset once, so relative offsets do not move with later publishes.
+func resolveOffset(offset string) (string, error) {
+	resolved, err := util.ResolveOffset(offset, time.Now())
+	if err != nil {
+		return offset, fmt.Errorf("%w: %s", ErrInvalidExport, err.Error())
+	}
+	return resolved, nil
+}
+
+func (f *Serving) startStoppedInstanceSweep(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(stoppedInstanceSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := f.SweepStoppedInstances(); err != nil {
+				util.Logger.Error("sweeping stopped exports failed", "error", err)
+			}
+		}
+	}()
+}
+
+// SweepStoppedInstances removes stored data of deleted exports and truncates the data of replayed exports once the
+// removal of their filter has been published, so the export worker stopped writing. Failed removals of deleted
+// exports are retried by the next sweep, exports with a failed truncation are marked failed and can be resumed.
+// Removed export databases are deleted once no export references them.
+func (f *Serving) SweepStoppedInstances() error {
+	var instances lib.Instances
+	err := db.DB.Preload("Values").Preload("ExportDatabase", withRemoved).Where("state IN (?)", []string{lib.InstanceStateDeleting, lib.InstanceStateTruncating}).Find(&instances).Error
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, instance := range instances {
+		id := instance.ID.String()
+		delivered, err := db.OutboxDelivered(id)
+		if err != nil {
+			//the relay gave up on the filter removal, publish it again
+			util.Logger.Warn("republishing filter removal", "error", err, "id", id)
+			err = db.DB.Transaction(func(tx *gorm.DB) error {
+				return f.driver.DeleteInstance(tx, &instance)
+			})
+			if err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if !delivered {
+			continue
+		}
+		if instance.State == lib.InstanceStateDeleting {
+			err = f.removeInstanceData(instance)
+		} else {
+			err = f.finishTruncation(instance)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+		}
+	}
+	if err := removeUnreferencedExportDatabases(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// removeUnreferencedExportDatabases deletes removed export databases once their last export is gone.
+func removeUnreferencedExportDatabases() error {
+	var databases []lib.ExportDatabase
+	err := db.DB.Unscoped().Where("deleted_at IS NOT NULL AND NOT EXISTS (SELECT 1 FROM instances WHERE instances.export_database_id = export_databases.id)").Find(&databases).Error
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, database := range databases {
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			return removeExportDatabase(tx, database)
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		util.Logger.Debug("serving - removed export-database without exports - " + database.ID)
+	}
+	return errors.Join(errs...)
+}
+
+func (f *Serving) removeInstanceData(instance lib.Instance) error {
+	var errs []error
+	switch instance.ExportDatabase.Type {
+	case "influxdb", "influxdb2":
+		errs = f.influx.ForceDeleteMeasurement(instance.ID.String(), "", instance)
+	case "timescaledb", "postgres":
+		errs = f.timescale.DropTable(instance)
+	}
+	if err := errors.Join(errs...); err != nil {
+		_ = setInstanceState(db.DB, &instance, lib.InstanceStateDeleting, err)
+		return err
+	}
+	err := errors.Join(db.DB.Delete(&instance).GetErrors()...)
+	if err == nil {
+		util.Logger.Debug("serving - removed data of deleted export - " + instance.ID.String())
+	}
+	return err
+}
+
+func (f *Serving) finishTruncation(instance lib.Instance) error {
+	err := errors.Join(f.truncateInstanceData(instance)...)
+	if err != nil {
+		//without filter the export can be resumed once the target is reachable again
+		_ = setInstanceState(db.DB, &instance, lib.InstanceStateFailed, err)
+		return err
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if e := f.CreateFromInstance(tx, &instance); e != nil {
+			return e
+		}
+		return setInstanceState(tx, &instance, lib.InstanceStatePending, nil)
+	})
+}
+
+func (f *Serving) truncateInstanceData(instance lib.Instance) (errs []error) {
+	switch instance.ExportDatabase.Type {
+	case "influxdb", "influxdb2":
+		return f.influx.ForceDeleteMeasurement(instance.ID.String(), "", instance)
+	case "timescaledb", "postgres":
+		return f.timescale.TruncateTable(instance)
+	}
+	return
+}
+
+// MoveInstance retargets an export to a compatible export database. The filter is published on the filter topic of
+// the new export database and removed from the old one, stored data stays in the old export database.
+func (f *Serving) MoveInstance(id string, req lib.MoveRequest, userId string, token string) (instance lib.Instance, err error) {
+	_, target, err := f.checkMove(id, req, userId, token)
+	if err != nil {
+		return
+	}
+	instance, err = f.moveInstance(id, target, userId, token)
+	if err != nil {
+		return
+	}
+	util.Logger.Debug("serving - moved export - "+id, "target", target.ID)
+	return
+}
+
+// checkMove returns the export and the export database it can be moved to.
+func (f *Serving) checkMove(id string, req lib.MoveRequest, userId string, token string) (instance lib.Instance, target lib.ExportDatabase, err error) {
+	instance, err = f.getWritableInstance(id, userId, token)
+	if err != nil {
+		return
+	}
+	//trashed exports can be moved, so they stay restorable once their export database is removed
+	if instance.State == lib.InstanceStateDeleting {
+		return instance, target, fmt.Errorf("%w: export is being deleted", ErrInvalidState)
+	}
+	if instance.State == lib.InstanceStateTruncating {
+		return instance, target, fmt.Errorf("%w: stored data is being truncated", ErrInvalidState)
+	}
+	target, err = f.getMoveTarget(instance.ExportDatabase, req.ExportDatabaseID, userId, token)
+	return
+}
+
+func (f *Serving) copyInstanceData(from lib.Instance, to lib.Instance, progress func(percent int)) error {
+	switch from.ExportDatabase.Type {
+	case "influxdb", "influxdb2":
+		return f.influx.CopyMeasurement(from, to, progress)
+	case "timescaledb", "postgres":
+		return f.timescale.CopyTable(from, to, progress)
+	}
+	return nil
+}
+
+// trashInstance removes the filter of an export from the export worker and moves it into the trash. Stored data is
+// kept until the export is purged.
+func (f *Serving) trashInstance(id string, userId string) (deleted bool, errs []error) {
+	query := db.DB.Where("id = ?", id)
+	if userId != "" {
+		query = db.DB.Where("id = ? AND user_id = ?", id, userId)
+	}
+	instance := lib.Instance{}
+	err := query.Preload("ExportDatabase").First(&instance).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, []error{err}
+	}
+	if instance.State == lib.InstanceStateTrashed {
+		return true, nil
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		//paused exports have no filter
+		if instance.State != lib.InstanceStatePaused {
+			if e := f.driver.DeleteInstance(tx, &instance); e != nil {
+				return e
+			}
+		}
+		if e := setInstanceState(tx, &instance, lib.InstanceStateTrashed, nil); e != nil {
+			return e
+		}
+		return tx.Model(&instance).UpdateColumn("trashed_at", instance.StateChangedAt).Error
+	})
+	if err != nil {
+		return false, []error{err}
+	}
+	util.Logger.Debug("serving - moved export to trash - " + id)
+	return true, nil
+}
+
+// RestoreInstance takes an export out of the trash and republishes its filter. Exports of a removed export database
+// have to be moved first.
+func (f *Serving) RestoreInstance(id string, userId string, token string) (instance lib.Instance, err error) {
+	instance, err = f.getWritableInstance(id, userId, token)
+	if err != nil {
+		return
+	}
+	if instance.State != lib.InstanceStateTrashed {
+		return instance, fmt.Errorf("%w: only trashed exports can be restored", ErrInvalidState)
+	}
+	if instance.ExportDatabase.DeletedAt != nil {
+		return instance, fmt.Errorf("%w: export-database was removed, move the export to another export-database first", ErrInvalidState)
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if e := f.CreateFromInstance(tx, &instance); e != nil {
+			return e
+		}
+		if e := setInstanceState(tx, &instance, lib.InstanceStatePending, nil); e != nil {
+			return e
+		}
+		instance.TrashedAt = nil
+		fields := map[string]interface{}{"trashed_at": gorm.Expr("NULL")}
+		//otherwise an expired export would be deleted again right away
+		if instance.ExpiresAt != nil && instance.ExpiresAt.Before(time.Now()) {
+			instance.ExpiresAt = nil
+			fields["expires_at"] = gorm.Expr("NULL")
+		}
+		return tx.Model(&instance).UpdateColumns(fields).Error
+	})
+	if err != nil {
+		return
+	}
+	util.Logger.Debug("serving - restored export - " + id)
+	return
+}
+
+// PurgeTrashedInstances deletes exports including their stored data once they are in the trash for longer than the
+// purge delay.
+func (f *Serving) PurgeTrashedInstances() error {
+	var instances lib.Instances
+	err := db.DB.Select("id").Where("state = ? AND trashed_at < ?", lib.InstanceStateTrashed, time.Now().Add(-f.purgeDelay)).Find(&instances).Error
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, instance := range instances {
+		id := instance.ID.String()
+		_, e := func() (bool, []error) {
+			if f.permissionsV2 != nil {
+				f.permMux.RLock()
+				defer f.permMux.RUnlock()
+			}
+			return f.purgeInstance(id, "")
+		}()
+		if len(e) > 0 {
+			errs = append(errs, e...)
+			continue
+		}
+		util.Logger.Debug("serving - purged export - " + id)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// GetRevisions lists all recorded definitions of a readable export, newest first.
+func (f *Serving) GetRevisions(id string, userId string, token string) (revisions []lib.Revision, err error) {
+	_, errs := f.GetInstance(id, userId, token, false)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	var stored []lib.InstanceRevision
+	err = db.DB.Where("instance_id = ?", id).Order("revision desc").Find(&stored).Error
+	if err != nil {
+		return
+	}
+	revisions = []lib.Revision{}
+	for _, s := range stored {
+		revision := lib.Revision{
+			Revision:  s.Revision,
+			UserId:    s.UserId,
+			CreatedAt: s.CreatedAt,
+			Changes:   []lib.RevisionChange{},
+		}
+		err = json.Unmarshal([]byte(s.Request), &revision.Request)
+		if err != nil {
+			return nil, err
+		}
+		if s.Changes != "" {
+			err = json.Unmarshal([]byte(s.Changes), &revision.Changes)
+			if err != nil {
+				return nil, err
+			}
+		}
+		revisions = append(revisions, revision)
+	}
+	return
+}
+
+// RestoreRevision applies a recorded definition through the regular update path, which resolves its offset again.
+func (f *Serving) RestoreRevision(id string, revision int, userId string, token string) (instance lib.Instance, errs []error) {
+	var stored lib.InstanceRevision
+	err := errors.Join(db.DB.Where("instance_id = ? AND revision = ?", id, revision).First(&stored).GetErrors()...)
+	if err != nil {
+		return instance, []error{err}
+	}
+	request, err := restoredRequest(stored, time.Now())
+	if err != nil {
+		return instance, []error{err}
+	}
+	return f.UpdateInstance(id, userId, request, token)
+}
+
+// restoredRequest returns the definition of a revision. An expiry that has passed since is removed, otherwise the
+// restored export would be deleted by the next expiry run.
+func restoredRequest(revision lib.InstanceRevision, now time.Time) (request lib.ServingRequest, err error) {
+	err = json.Unmarshal([]byte(revision.Request), &request)
+	if err != nil {
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(now) {
+		request.ExpiresAt = nil
+	}
+	return
+}
+
+// addRevision records req as next revision of an export. Changes are computed against the previous definition.
+func addRevision(tx *gorm.DB, id uuid.UUID, userId string, previous *lib.Instance, req lib.ServingRequest) (err error) {
+	req.ResetData = false
+	req.ForceUpdate = false
+	var changes []lib.RevisionChange
+	if previous != nil {
+		changes = revisionChanges(servingRequestFromInstance(*previous), req)
+	}
+	request, err := json.Marshal(req)
+	if err != nil {
+		return
+	}
+	changesJson, err := json.Marshal(changes)
+	if err != nil {
+		return
+	}
+	var last lib.InstanceRevision
+	err = tx.Where("instance_id = ?", id).Order("revision desc").First(&last).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return
+	}
+	return tx.Create(&lib.InstanceRevision{
+		InstanceID: id,
+		Revision:   last.Revision + 1,
+		UserId:     userId,
+		Request:    string(request),
+		Changes:    string(changesJson),
+	}).Error
+}
+
+func deleteRevisions(id string) error {
+	return db.DB.Where("instance_id = ?", id).Delete(&lib.InstanceRevision{}).Error
+}
+
+func servingRequestFromInstance(instance lib.Instance) (req lib.ServingRequest) {
+	req = lib.ServingRequest{
+		FilterType:       instance.FilterType,
+		Filter:           instance.Filter,
+		Name:             instance.Name,
+		EntityName:       instance.EntityName,
+		ServiceName:      instance.ServiceName,
+		Description:      instance.Description,
+		Topic:            instance.Topic,
+		TimePath:         instance.TimePath,
+		Generated:        instance.Generated,
+		Offset:           instance.Offset,
+		Values:           servingRequestValues(instance.Values),
+		ExportDatabaseID: instance.ExportDatabaseID,
+		TimestampFormat:  instance.TimestampFormat,
+		TimestampUnique:  instance.TimestampUnique,
+		ExpiresAt:        instance.ExpiresAt,
+	}
+	if instance.TimePrecision != nil {
+		req.TimePrecision = *instance.TimePrecision
+	}
+	return
+}
+
+func revisionChanges(old lib.ServingRequest, new lib.ServingRequest) (changes []lib.RevisionChange) {
+	o := reflect.ValueOf(old)
+	n := reflect.ValueOf(new)
+	for i := 0; i < o.NumField(); i++ {
+		field := o.Type().Field(i).Name
+		if field == "Values" || field == "ResetData" || field == "ForceUpdate" {
+			continue
+		}
+		if field == "ExpiresAt" {
+			if !timeEqual(old.ExpiresAt, new.ExpiresAt) {
+				changes = append(changes, lib.RevisionChange{Field: field, Old: old.ExpiresAt, New: new.ExpiresAt})
+			}
+			continue
+		}
+		if o.Field(i).Interface() != n.Field(i).Interface() {
+			changes = append(changes, lib.RevisionChange{Field: field, Old: o.Field(i).Interface(), New: n.Field(i).Interface()})
+		}
+	}
+	values := map[string]lib.ServingRequestValue{}
+	for _, v := range old.Values {
+		values[v.Name] = v
+	}
+	for _, v := range new.Values {
+		o, ok := values[v.Name]
+		if !ok {
+			changes = append(changes, lib.RevisionChange{Field: "Values." + v.Name, New: v})
+		} else if o != v {
+			changes = append(changes, lib.RevisionChange{Field: "Values." + v.Name, Old: o, New: v})
+		}
+		delete(values, v.Name)
+	}
+	for _, v := range old.Values {
+		if _, ok := values[v.Name]; ok {
+			changes = append(changes, lib.RevisionChange{Field: "Values." + v.Name, Old: v})
+		}
+	}
+	return
+}
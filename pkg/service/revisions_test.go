@@ -0,0 +1,95 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+)
+
+func TestRevisionChanges(t *testing.T) {
+	a := lib.ServingRequestValue{Name: "a", Type: "float", Path: "value.a"}
+	b := lib.ServingRequestValue{Name: "b", Type: "string", Path: "value.b", Tag: true}
+	old := lib.ServingRequest{Name: "export", Filter: "device", FilterType: "deviceId", Offset: "largest", Values: []lib.ServingRequestValue{a, b}}
+
+	if changes := revisionChanges(old, old); changes != nil {
+		t.Error("expected no changes, got", changes)
+	}
+
+	// flags of a single update and the order of values are not part of a revision
+	updated := old
+	updated.ForceUpdate = true
+	updated.ResetData = true
+	updated.Values = []lib.ServingRequestValue{b, a}
+	if changes := revisionChanges(old, updated); changes != nil {
+		t.Error("expected no changes, got", changes)
+	}
+
+	taggedA := a
+	taggedA.Tag = true
+	c := lib.ServingRequestValue{Name: "c", Type: "int", Path: "value.c"}
+	updated.Name = "renamed"
+	updated.Values = []lib.ServingRequestValue{taggedA, c}
+	expected := []lib.RevisionChange{
+		{Field: "Name", Old: "export", New: "renamed"},
+		{Field: "Values.a", Old: a, New: taggedA},
+		{Field: "Values.c", New: c},
+		{Field: "Values.b", Old: b},
+	}
+	if changes := revisionChanges(old, updated); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, changes)
+	}
+}
+
+func TestRevisionChangesExpiry(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	sameInstant := expiresAt.In(time.FixedZone("CET", 3600))
+	if changes := revisionChanges(lib.ServingRequest{ExpiresAt: &expiresAt}, lib.ServingRequest{ExpiresAt: &sameInstant}); changes != nil {
+		t.Error("the same instant in another zone is no change, got", changes)
+	}
+	changes := revisionChanges(lib.ServingRequest{ExpiresAt: &expiresAt}, lib.ServingRequest{})
+	if len(changes) != 1 || changes[0].Field != "ExpiresAt" || changes[0].New != (*time.Time)(nil) {
+		t.Error("expected the removed expiry, got", changes)
+	}
+}
+
+func TestRestoredRequest(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	revision := lib.InstanceRevision{Request: `{"Name":"export","Offset":"2025-01-01T00:00:00Z","ExpiresAt":"2025-05-01T00:00:00Z"}`}
+	request, err := restoredRequest(revision, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.ExpiresAt != nil {
+		t.Error("a passed expiry must not be restored, got", request.ExpiresAt)
+	}
+	if request.Name != "export" || request.Offset != "2025-01-01T00:00:00Z" {
+		t.Error("unexpected request", request)
+	}
+
+	revision.Request = `{"Name":"export","ExpiresAt":"2025-07-01T00:00:00Z"}`
+	request, err = restoredRequest(revision, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.ExpiresAt == nil || !request.ExpiresAt.Equal(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("a future expiry must be kept, got", request.ExpiresAt)
+	}
+}
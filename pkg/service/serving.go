@@ -17,6 +17,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -24,6 +25,7 @@ import (
 	"time"
 
 	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
 	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
 	permV2Client "github.com/SENERGY-Platform/permissions-v2/pkg/client"
@@ -36,43 +38,63 @@ import (
 type Serving struct {
 	driver                 Driver
 	influx                 Influx
+	timescale              Timescale
 	pipelineService        PipelineApiService
 	importDeployService    ImportDeployService
+	notifier               NotificationService
 	exportDatabaseIdPrefix string
 	permissionsV2          permV2Client.Client
 	permMux                sync.RWMutex
+	purgeDelay             time.Duration
+	expiryWarning          time.Duration
+	jobNotify              chan struct{}
 }
 
 func NewServing(driver Driver,
 	influx Influx,
+	timescale Timescale,
 	pipelineService PipelineApiService,
 	importDeployService ImportDeployService,
+	notifier NotificationService,
 	exportDatabaseIdPrefix string,
 	permissionsV2 permV2Client.Client,
 	cleanupChron string,
-	cleanupRecheckWait time.Duration) (*Serving, error) {
+	cleanupRecheckWait time.Duration,
+	purgeChron string,
+	purgeDelay time.Duration,
+	expiryChron string,
+	expiryWarning time.Duration,
+	jobConfig config.JobConfig,
+	ctx context.Context,
+	wg *sync.WaitGroup) (*Serving, error) {
 	if permissionsV2 != nil {
-		_, err, _ := permissionsV2.SetTopic(permV2Client.InternalAdminToken, permV2Client.Topic{
-			Id: ExportInstancePermissionsTopic,
-		})
-		if err != nil {
-			return nil, err
+		for _, topic := range []string{ExportInstancePermissionsTopic, ExportDatabasePermissionsTopic} {
+			_, err, _ := permissionsV2.SetTopic(permV2Client.InternalAdminToken, permV2Client.Topic{
+				Id: topic,
+			})
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 	result := &Serving{
 		driver:                 driver,
 		influx:                 influx,
+		timescale:              timescale,
 		pipelineService:        pipelineService,
 		importDeployService:    importDeployService,
+		notifier:               notifier,
 		exportDatabaseIdPrefix: exportDatabaseIdPrefix,
 		permissionsV2:          permissionsV2,
+		purgeDelay:             purgeDelay,
+		expiryWarning:          expiryWarning,
 	}
 	err := result.ExportInstanceCleanup(cleanupRecheckWait)
 	if err != nil {
 		return nil, err
 	}
+	runner := cron.New()
 	if cleanupChron != "" && cleanupChron != "-" {
-		runner := cron.New()
 		_, err = runner.AddFunc(cleanupChron, func() {
 			err = result.ExportInstanceCleanup(cleanupRecheckWait)
 			if err != nil {
@@ -82,8 +104,37 @@ func NewServing(driver Driver,
 		if err != nil {
 			return nil, err
 		}
+	}
+	if purgeDelay > 0 && purgeChron != "" && purgeChron != "-" {
+		_, err = runner.AddFunc(purgeChron, func() {
+			err := result.PurgeTrashedInstances()
+			if err != nil {
+				util.Logger.Error("purge fail", "error", err)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if expiryChron != "" && expiryChron != "-" {
+		_, err = runner.AddFunc(expiryChron, func() {
+			err := result.ReapExpiredInstances()
+			if err != nil {
+				util.Logger.Error("expiry fail", "error", err)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(runner.Entries()) > 0 {
 		runner.Start()
 	}
+	result.startStoppedInstanceSweep(ctx, wg)
+	err = result.startJobWorkers(ctx, wg, jobConfig)
+	if err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
@@ -92,7 +143,14 @@ func (f *Serving) CreateInstance(req lib.ServingRequest, userId string, token st
 	if !access {
 		return
 	}
-	id := uuid.New()
+	return f.createInstance(uuid.New(), req, userId, token)
+}
+
+func (f *Serving) createInstance(id uuid.UUID, req lib.ServingRequest, userId string, token string) (instance lib.Instance, err error) {
+	req.Offset, err = resolveOffset(req.Offset)
+	if err != nil {
+		return
+	}
 	appId := uuid.New()
 
 	if f.permissionsV2 != nil {
@@ -102,60 +160,139 @@ func (f *Serving) CreateInstance(req lib.ServingRequest, userId string, token st
 			permV2Client.InternalAdminToken,
 			ExportInstancePermissionsTopic,
 			id.String(),
-			permV2Client.ResourcePermissions{
-				UserPermissions:  map[string]permV2Client.PermissionsMap{userId: {Read: true, Write: true, Execute: true, Administrate: true}},
-				GroupPermissions: map[string]permV2Client.PermissionsMap{},
-				RolePermissions:  map[string]permV2Client.PermissionsMap{},
-			})
+			instancePermissions(userId))
 		if err != nil {
 			return instance, err
 		}
 	}
 
-	instance, err = f.createInstanceWithId(id, appId, req, userId)
+	instance, err = f.createInstanceWithId(id, appId, req, userId, userId, nil, token)
 	if err != nil {
-		if f.permissionsV2 != nil {
-			temperr, _ := f.permissionsV2.RemoveResource(permV2Client.InternalAdminToken, ExportInstancePermissionsTopic, id.String())
-			util.Logger.Error("remove inconsistent permission", "error", err, "temperr", temperr)
-		}
+		f.removeInconsistentPermission(id.String(), err)
 		return instance, err
 	}
 	return
 }
 
-func (f *Serving) createInstanceWithId(id uuid.UUID, appId uuid.UUID, req lib.ServingRequest, userId string) (instance lib.Instance, err error) {
-	database, errs := f.GetExportDatabase(req.ExportDatabaseID, userId)
+// instancePermissions are the initial permissions of an export owned by userId.
+func instancePermissions(userId string) permV2Client.ResourcePermissions {
+	return permV2Client.ResourcePermissions{
+		UserPermissions:  map[string]permV2Client.PermissionsMap{userId: {Read: true, Write: true, Execute: true, Administrate: true}},
+		GroupPermissions: map[string]permV2Client.PermissionsMap{},
+		RolePermissions:  map[string]permV2Client.PermissionsMap{},
+	}
+}
+
+// removeInconsistentPermission removes the permissions of an export that could not be created.
+func (f *Serving) removeInconsistentPermission(id string, cause error) {
+	if f.permissionsV2 != nil {
+		temperr, _ := f.permissionsV2.RemoveResource(permV2Client.InternalAdminToken, ExportInstancePermissionsTopic, id)
+		util.Logger.Error("remove inconsistent permission", "error", cause, "temperr", temperr)
+	}
+}
+
+// CloneInstance creates a new export from the definition of a readable export. Source access is checked again for
+// the resulting request.
+func (f *Serving) CloneInstance(id string, req lib.CloneRequest, userId string, token string) (instance lib.Instance, err error) {
+	source, errs := f.GetInstance(id, userId, token, false)
+	if len(errs) > 0 {
+		return instance, errors.Join(errs...)
+	}
+	request := servingRequestFromInstance(source)
+	request.ExpiresAt = nil
+	if req.Name != "" {
+		request.Name = req.Name
+	}
+	if req.Filter != "" {
+		request.Filter = req.Filter
+	}
+	if req.Topic != "" {
+		request.Topic = req.Topic
+	}
+	if req.ExportDatabaseID != "" {
+		request.ExportDatabaseID = req.ExportDatabaseID
+	}
+	return f.CreateInstance(request, userId, token)
+}
+
+// PreviewInstance renders the driver specific representation of an export without creating it.
+func (f *Serving) PreviewInstance(req lib.ServingRequest, userId string, token string) (preview interface{}, err error) {
+	previewer, ok := f.driver.(DriverPreview)
+	if !ok {
+		return nil, ErrPreviewUnsupported
+	}
+	database, errs := f.GetExportDatabase(req.ExportDatabaseID, userId, token)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: export-database does not exist or user unauthorized", ErrInvalidExport)
+	}
+	instance, dataFields, tagFields := populateInstance(uuid.New(), uuid.New(), req, userId)
+	instance.ExportDatabase = database
+	return previewer.PreviewInstance(&instance, dataFields, tagFields)
+}
+
+// TestMapping applies an existing or a requested export to a sample message.
+func (f *Serving) TestMapping(req lib.MappingTestRequest, userId string, token string) (result lib.MappingTestResult, err error) {
+	tester, ok := f.driver.(DriverMappingTest)
+	if !ok {
+		return result, ErrMappingTestUnsupported
+	}
+	var instance lib.Instance
+	var dataFields, tagFields string
+	if req.InstanceID != "" {
+		var errs []error
+		instance, errs = f.GetInstance(req.InstanceID, userId, token, false)
+		if len(errs) > 0 {
+			return result, errors.Join(errs...)
+		}
+		_, dataFields, tagFields = transformServingValues(instance.ID, servingRequestValues(instance.Values))
+	} else if req.Request != nil {
+		database, errs := f.GetExportDatabase(req.Request.ExportDatabaseID, userId, token)
+		if len(errs) > 0 {
+			return result, fmt.Errorf("%w: export-database does not exist or user unauthorized", ErrInvalidExport)
+		}
+		instance, dataFields, tagFields = populateInstance(uuid.New(), uuid.New(), *req.Request, userId)
+		instance.ExportDatabase = database
+	} else {
+		return result, fmt.Errorf("%w: either an instance id or a request is required", ErrInvalidExport)
+	}
+	return tester.TestMapping(&instance, dataFields, tagFields, req.Sample)
+}
+
+// createInstanceWithId creates an export owned by userId and records the request as revision of author. previous is the
+// replaced definition if an export is recreated. The export database has to be readable with token.
+func (f *Serving) createInstanceWithId(id uuid.UUID, appId uuid.UUID, req lib.ServingRequest, userId string, author string, previous *lib.Instance, token string) (instance lib.Instance, err error) {
+	database, errs := f.GetExportDatabase(req.ExportDatabaseID, userId, token)
 	if len(errs) > 0 {
 		err = errors.New("export-database does not exist or user unauthorized")
 		return
 	}
 	instance, dataFields, tagFields := populateInstance(id, appId, req, userId)
 	instance.ExportDatabase = database
-	err = util.Retry(5, 5*time.Second, func() (err error) {
-		serviceId, err := f.driver.CreateInstance(&instance, dataFields, tagFields)
-		if err == nil {
-			instance.RancherServiceId = serviceId
+	now := time.Now()
+	//the export stays pending until the outbox relay published its filter
+	instance.State = lib.InstanceStatePending
+	instance.StateChangedAt = &now
+	db.DB.NewRecord(instance)
+	err = db.DB.Transaction(func(tx *gorm.DB) (err error) {
+		err = errors.Join(tx.Create(&instance).GetErrors()...)
+		if err != nil {
+			return
 		}
-		return
+		err = addRevision(tx, id, author, previous, req)
+		if err != nil {
+			return
+		}
+		instance.RancherServiceId, err = f.driver.CreateInstance(tx, &instance, dataFields, tagFields)
+		if err != nil || instance.RancherServiceId == "" {
+			return
+		}
+		return tx.Model(&instance).UpdateColumn("rancher_service_id", instance.RancherServiceId).Error
 	})
 	if err != nil {
+		err = errors.New("serving - creating export failed - " + err.Error())
 		return
-	} else {
-		db.DB.NewRecord(instance)
-		errs = db.DB.Create(&instance).GetErrors()
-		if len(errs) > 0 {
-			err2 := util.Retry(5, 5*time.Second, func() (e error) {
-				e = f.driver.DeleteInstance(&instance)
-				return
-			})
-			if err2 != nil {
-				errs = append(errs, err2)
-			}
-			err = errors.New("serving - creating export failed - " + fmt.Sprint(errs))
-			return
-		}
-		util.Logger.Debug("serving - successfully created export - " + instance.ID.String())
 	}
+	util.Logger.Debug("serving - successfully created export - " + instance.ID.String())
 	return
 }
 
@@ -165,22 +302,25 @@ func (f *Serving) UpdateInstance(id string, userId string, request lib.ServingRe
 		errors = append(errors, err)
 		return
 	}
-	query := db.DB.Where("id = ? AND user_id = ?", id, userId)
-	if f.permissionsV2 != nil {
-		access, err, _ = f.permissionsV2.CheckPermission(token, ExportInstancePermissionsTopic, id, permV2Client.Write)
-		if err != nil {
-			return instance, []error{err}
-		}
-		if !access {
-			return instance, []error{fmt.Errorf("access denied")}
-		}
-		query = db.DB.Where("id = ?", id)
+	instance, err = f.getWritableInstance(id, userId, token)
+	if err != nil {
+		return instance, []error{err}
 	}
-	errors = query.Preload("Values").Preload("ExportDatabase").First(&instance).GetErrors()
-	if len(errors) > 0 {
-		return
+	return f.updateInstance(instance, userId, request, token)
+}
+
+func (f *Serving) updateInstance(instance lib.Instance, userId string, request lib.ServingRequest, token string) (lib.Instance, []error) {
+	if instance.State == lib.InstanceStateTrashed {
+		return instance, []error{fmt.Errorf("%w: export is in trash", ErrInvalidState)}
+	}
+	if instance.State == lib.InstanceStateTruncating {
+		return instance, []error{fmt.Errorf("%w: stored data is being truncated", ErrInvalidState)}
+	}
+	var err error
+	request.Offset, err = resolveOffset(request.Offset)
+	if err != nil {
+		return instance, []error{err}
 	}
-	uid, _ := uuid.Parse(id)
 	appId := instance.ApplicationId
 	if appId.ID() == 0 {
 		appId = uuid.New()
@@ -188,30 +328,151 @@ func (f *Serving) UpdateInstance(id string, userId string, request lib.ServingRe
 	if request.Offset != instance.Offset {
 		appId = uuid.New()
 	}
-	requestInstance, _, _ := populateInstance(uid, appId, request, userId)
+	if request.ResetData {
+		reset, err := f.resetInstance(instance, request, appId, userId, token)
+		if err != nil {
+			return reset, []error{err}
+		}
+		return reset, nil
+	}
+	//owner and target database stay untouched, otherwise existing data would no longer be reachable
+	requestInstance, dataFields, tagFields := populateInstance(instance.ID, appId, request, instance.UserId)
+	requestInstance.Database = instance.Database
 	requestInstance.RancherServiceId = instance.RancherServiceId
 	requestInstance.CreatedAt = instance.CreatedAt
-	instance = f.update(id, userId, request, requestInstance, uid, appId)
-	return
+	requestInstance.ExportDatabase = instance.ExportDatabase
+	requestInstance.State = instance.State
+	requestInstance.StateChangedAt = instance.StateChangedAt
+	requestInstance.LastError = instance.LastError
+	requestInstance.LastErrorAt = instance.LastErrorAt
+	if timeEqual(requestInstance.ExpiresAt, instance.ExpiresAt) {
+		requestInstance.ExpiryNotifiedAt = instance.ExpiryNotifiedAt
+	}
+	if requestInstance.ExportDatabaseID != instance.ExportDatabaseID {
+		var errs []error
+		requestInstance.ExportDatabase, errs = f.GetExportDatabase(requestInstance.ExportDatabaseID, userId, token)
+		if len(errs) > 0 {
+			return instance, []error{fmt.Errorf("export-database does not exist or user unauthorized")}
+		}
+	}
+	updated, err := f.update(instance, requestInstance, dataFields, tagFields, request, userId)
+	if err != nil {
+		return updated, []error{err}
+	}
+	return updated, nil
 }
 
-func (f *Serving) update(id string, userId string, request lib.ServingRequest, instance lib.Instance, uid uuid.UUID, appId uuid.UUID) lib.Instance {
-	err := util.Retry(5, 5*time.Second, func() (err error) {
+// update applies a changed export definition in place. The export-worker filter is only republished
+// if the mapping changed, metadata is written to the database only and stored data is never dropped.
+// Filter messages and the revision are recorded in the same transaction as the changed definition.
+func (f *Serving) update(old lib.Instance, instance lib.Instance, dataFields string, tagFields string, request lib.ServingRequest, userId string) (lib.Instance, error) {
+	//paused exports have no filter, the changed definition is published on resume
+	paused := old.State == lib.InstanceStatePaused
+	databaseChanged := old.ExportDatabaseID != instance.ExportDatabaseID
+	err := db.DB.Transaction(func(tx *gorm.DB) (err error) {
+		if databaseChanged && !paused {
+			err = f.driver.DeleteInstance(tx, &old)
+			if err != nil {
+				return
+			}
+		}
+		if !paused && (databaseChanged || exportFilterChanged(old, instance)) {
+			instance.RancherServiceId, err = f.driver.CreateInstance(tx, &instance, dataFields, tagFields)
+			if err != nil {
+				return
+			}
+			if instance.State != lib.InstanceStatePending {
+				now := time.Now()
+				instance.State = lib.InstanceStatePending
+				instance.StateChangedAt = &now
+			}
+		}
+		err = addRevision(tx, instance.ID, userId, &old, request)
+		if err != nil {
+			return
+		}
+		return saveInstance(tx, &instance)
+	})
+	if err != nil {
+		return old, errors.New("serving - updating export failed - " + err.Error())
+	}
+	util.Logger.Debug("serving - successfully updated export - " + instance.ID.String())
+	return instance, nil
+}
+
+// resetInstance drops the export including all stored data and recreates it with the same id.
+func (f *Serving) resetInstance(old lib.Instance, request lib.ServingRequest, appId uuid.UUID, userId string, token string) (instance lib.Instance, err error) {
+	err = util.Retry(5, 5*time.Second, func() (err error) {
 		//we use an empty userId to indicate that the user should not be checked
 		//the check has already been done by UpdateInstance()
-		_, errs := f.deleteInstance(id, "")
-		if len(errs) > 1 {
-			err = errs[0]
-		}
-		return err
+		_, errs := f.deleteInstance(old.ID.String(), "")
+		return errors.Join(errs...)
 	})
 	if err != nil {
 		util.Logger.Error("error on update", "error", err)
-	} else {
-		instance, _ = f.createInstanceWithId(uid, appId, request, userId)
-		util.Logger.Debug("serving - successfully updated export - " + instance.ID.String())
+		return old, err
 	}
-	return instance
+	instance, err = f.createInstanceWithId(old.ID, appId, request, old.UserId, userId, &old, token)
+	if err != nil {
+		return
+	}
+	util.Logger.Debug("serving - successfully reset export - " + instance.ID.String())
+	return
+}
+
+// setInstanceState persists a lifecycle state change. A given cause is recorded as last error.
+func setInstanceState(tx *gorm.DB, instance *lib.Instance, state string, cause error) error {
+	now := time.Now()
+	fields := map[string]interface{}{"state": state, "state_changed_at": now}
+	instance.State = state
+	instance.StateChangedAt = &now
+	if cause != nil {
+		fields["last_error"] = cause.Error()
+		fields["last_error_at"] = now
+		instance.LastError = cause.Error()
+		instance.LastErrorAt = &now
+	}
+	return tx.Model(&lib.Instance{}).Where("id = ?", instance.ID).UpdateColumns(fields).Error
+}
+
+func saveInstance(tx *gorm.DB, instance *lib.Instance) (err error) {
+	errs := tx.Where("instance_id = ?", instance.ID).Delete(&lib.Value{}).GetErrors()
+	if len(errs) == 0 {
+		errs = tx.Save(instance).GetErrors()
+	}
+	return errors.Join(errs...)
+}
+
+func exportFilterChanged(a lib.Instance, b lib.Instance) bool {
+	if a.Topic != b.Topic ||
+		a.Filter != b.Filter ||
+		a.FilterType != b.FilterType ||
+		a.TimePath != b.TimePath ||
+		a.TimestampFormat != b.TimestampFormat ||
+		a.TimestampUnique != b.TimestampUnique ||
+		a.Database != b.Database ||
+		a.Offset != b.Offset ||
+		a.ApplicationId != b.ApplicationId {
+		return true
+	}
+	if (a.TimePrecision == nil) != (b.TimePrecision == nil) ||
+		(a.TimePrecision != nil && *a.TimePrecision != *b.TimePrecision) {
+		return true
+	}
+	if len(a.Values) != len(b.Values) {
+		return true
+	}
+	values := map[string]lib.Value{}
+	for _, v := range a.Values {
+		values[v.Name] = v
+	}
+	for _, v := range b.Values {
+		o, ok := values[v.Name]
+		if !ok || o.Type != v.Type || o.Path != v.Path || o.Tag != v.Tag || o.PrimaryKey != v.PrimaryKey {
+			return true
+		}
+	}
+	return false
 }
 
 func (f *Serving) GetInstance(id string, userId string, token string, admin bool) (instance lib.Instance, errors []error) {
@@ -264,6 +525,11 @@ func (f *Serving) GetInstances(userId string, args map[string][]string, admin bo
 			countTx = DB.Where("id IN (?)", ids)
 		}
 	}
+	//trashed exports are only listed if requested explicitly
+	if _, ok := args["state"]; !ok {
+		tx = tx.Where("state <> ?", lib.InstanceStateTrashed)
+		countTx = countTx.Where("state <> ?", lib.InstanceStateTrashed)
+	}
 	for arg, value := range args {
 		if arg == "limit" {
 			tx = tx.Limit(value[0])
@@ -298,14 +564,45 @@ func (f *Serving) GetInstances(userId string, args map[string][]string, admin bo
 				countTx = countTx.Where("`generated` = FALSE")
 			}
 		}
+		if arg == "state" {
+			states := strings.Split(value[0], ",")
+			tx = tx.Where("state IN (?)", states)
+			countTx = countTx.Where("state IN (?)", states)
+		}
+		if arg == "expiring" {
+			if value[0] == "true" {
+				tx = tx.Where("expires_at IS NOT NULL")
+				countTx = countTx.Where("expires_at IS NOT NULL")
+			} else {
+				tx = tx.Where("expires_at IS NULL")
+				countTx = countTx.Where("expires_at IS NULL")
+			}
+		}
+		if arg == "expires_before" {
+			if t, err := time.Parse(time.RFC3339, value[0]); err == nil {
+				tx = tx.Where("expires_at < ?", t)
+				countTx = countTx.Where("expires_at < ?", t)
+			}
+		}
+		if arg == "expires_after" {
+			if t, err := time.Parse(time.RFC3339, value[0]); err == nil {
+				tx = tx.Where("expires_at > ?", t)
+				countTx = countTx.Where("expires_at > ?", t)
+			}
+		}
 		if arg == "export_database_id" {
 			tx = tx.Where("export_database_id = ?", value[0])
 			countTx = countTx.Where("export_database_id = ?", value[0])
 		}
 		if arg == "internal_only" {
 			if value[0] == "true" {
-				tx = tx.Where("export_database_id IN (?)", DB.Table("export_databases").Select("id").Where("deployment = ? AND (public = TRUE OR user_id = ?)", "internal", userId).SubQuery())
-				countTx = countTx.Where("export_database_id IN (?)", DB.Table("export_databases").Select("id").Where("deployment = ? AND (public = TRUE OR user_id = ?)", "internal", userId).SubQuery())
+				readable, err := f.readableExportDatabases(userId, token)
+				if err != nil {
+					return instances, total, []error{err}
+				}
+				internal := readable.Select("id").Where("deployment = ?", DeploymentInternal).SubQuery()
+				tx = tx.Where("export_database_id IN (?)", internal)
+				countTx = countTx.Where("export_database_id IN (?)", internal)
 			}
 		}
 	}
@@ -354,7 +651,20 @@ func (f *Serving) DeleteInstanceWithPermHandling(id string, userId string, admin
 			return false, []error{fmt.Errorf("access denied")}
 		}
 	}
+	if f.purgeDelay > 0 {
+		return f.trashInstance(id, userId)
+	}
+	return f.purgeInstance(id, userId)
+}
+
+// purgeInstance deletes an export including its stored data, revisions and permissions.
+func (f *Serving) purgeInstance(id string, userId string) (deleted bool, errors []error) {
 	deleted, errors = f.deleteInstance(id, userId)
+	if deleted {
+		if err := deleteRevisions(id); err != nil {
+			util.Logger.Error("could not remove export revisions", "error", err, "id", id)
+		}
+	}
 	if len(errors) > 0 {
 		return deleted, errors
 	}
@@ -378,7 +688,7 @@ func (f *Serving) deleteInstance(id string, userId string) (deleted bool, errors
 	deleted = false
 	instance := lib.Instance{}
 
-	errors = tx.Preload("ExportDatabase").First(&instance).GetErrors()
+	errors = tx.Preload("ExportDatabase", withRemoved).First(&instance).GetErrors()
 	if len(errors) > 0 {
 		for _, e := range errors {
 			if gorm.IsRecordNotFoundError(e) {
@@ -388,44 +698,49 @@ func (f *Serving) deleteInstance(id string, userId string) (deleted bool, errors
 		}
 		return
 	}
-	err := util.Retry(5, 5*time.Second, func() (err error) {
-		err = f.driver.DeleteInstance(&instance)
-		return
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if e := f.driver.DeleteInstance(tx, &instance); e != nil {
+			return e
+		}
+		return setInstanceState(tx, &instance, lib.InstanceStateDeleting, nil)
 	})
 	if err != nil {
 		errors = append(errors, err)
 		return
-	} else {
-		deleted = true
-		errors = db.DB.Delete(&instance).GetErrors()
-		if instance.ExportDatabase.Type == "influxdb" {
-			errs := f.influx.ForceDeleteMeasurement(id, userId, instance)
-			if len(errs) > 0 {
-				for _, e := range errs {
-					errors = append(errors, e)
-				}
-			}
-		}
 	}
-	return deleted, errors
+	//stored data and the export are removed by SweepStoppedInstances once the export worker stopped writing
+	return true, nil
+}
+
+func (f *Serving) CreateFromInstance(tx *gorm.DB, instance *lib.Instance) (err error) {
+	var dataFields, tagFields string
+	_, dataFields, tagFields = transformServingValues(instance.ID, servingRequestValues(instance.Values))
+	instance.RancherServiceId, err = f.driver.CreateInstance(tx, instance, dataFields, tagFields)
+	return
 }
 
-func (f *Serving) CreateFromInstance(instance *lib.Instance) (err error) {
-	var servingRequestValues []lib.ServingRequestValue
-	for _, value := range instance.Values {
-		servingRequestValues = append(servingRequestValues, lib.ServingRequestValue{
-			Name: value.Name,
-			Type: value.Type,
-			Path: value.Path,
-			Tag:  value.Tag,
+func servingRequestValues(values []lib.Value) (requestValues []lib.ServingRequestValue) {
+	for _, value := range values {
+		requestValues = append(requestValues, lib.ServingRequestValue{
+			Name:       value.Name,
+			Type:       value.Type,
+			Path:       value.Path,
+			Tag:        value.Tag,
+			PrimaryKey: value.PrimaryKey,
 		})
 	}
-	var dataFields, tagFields string
-	_, dataFields, tagFields = transformServingValues(instance.ID, servingRequestValues)
-	instance.RancherServiceId, err = f.driver.CreateInstance(instance, dataFields, tagFields)
 	return
 }
 
+// checkSourceAccess is like userHasSourceAccess, but also returns an error for unsupported filter types.
+func (f *Serving) checkSourceAccess(req lib.ServingRequest, token string) error {
+	access, err := f.userHasSourceAccess(req, token)
+	if err == nil && !access {
+		err = errors.New("serving - unsupported filter type '" + req.FilterType + "'")
+	}
+	return err
+}
+
 func (f *Serving) userHasSourceAccess(req lib.ServingRequest, token string) (access bool, err error) {
 	access = false
 	switch req.FilterType {
@@ -488,6 +803,7 @@ func populateInstance(id uuid.UUID, appId uuid.UUID, req lib.ServingRequest, use
 		ExportDatabaseID: req.ExportDatabaseID,
 		TimestampFormat:  req.TimestampFormat,
 		TimestampUnique:  req.TimestampUnique,
+		ExpiresAt:        req.ExpiresAt,
 	}
 	if req.TimePrecision != "" {
 		instance.TimePrecision = &req.TimePrecision
@@ -502,7 +818,7 @@ func transformServingValues(id uuid.UUID, requestValues []lib.ServingRequestValu
 	dataFields = "{"
 	tagFields = "{"
 	for _, value := range requestValues {
-		values = append(values, lib.Value{InstanceID: id, Name: value.Name, Type: value.Type, Path: value.Path, Tag: value.Tag})
+		values = append(values, lib.Value{InstanceID: id, Name: value.Name, Type: value.Type, Path: value.Path, Tag: value.Tag, PrimaryKey: value.PrimaryKey})
 		if value.Tag {
 			if len(tagFields) > 1 {
 				tagFields = tagFields + ","
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"testing"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/google/uuid"
+)
+
+func TestExportFilterChanged(t *testing.T) {
+	precision := "ms"
+	current := lib.Instance{
+		Name:            "export",
+		Description:     "description",
+		Topic:           "topic",
+		Filter:          "device",
+		FilterType:      "deviceId",
+		TimePath:        "value.time",
+		TimePrecision:   &precision,
+		TimestampFormat: "unix",
+		Database:        "user",
+		Offset:          "largest",
+		ApplicationId:   uuid.New(),
+		Values: []lib.Value{
+			{Name: "a", Type: "float", Path: "value.a"},
+			{Name: "b", Type: "string", Path: "value.b", Tag: true},
+		},
+	}
+
+	updated := current
+	updated.Name = "renamed"
+	updated.Description = "other description"
+	updated.Values = []lib.Value{current.Values[1], current.Values[0]}
+	if exportFilterChanged(current, updated) {
+		t.Error("renaming an export or reordering its values must keep the filter")
+	}
+
+	updated.Offset = "smallest"
+	if !exportFilterChanged(current, updated) {
+		t.Error("changing the offset must change the filter")
+	}
+
+	updated = current
+	updated.ApplicationId = uuid.New()
+	if !exportFilterChanged(current, updated) {
+		t.Error("a new application id must change the filter")
+	}
+
+	updated = current
+	updated.TimePrecision = nil
+	if !exportFilterChanged(current, updated) {
+		t.Error("removing the time precision must change the filter")
+	}
+	seconds := "s"
+	updated.TimePrecision = &seconds
+	if !exportFilterChanged(current, updated) {
+		t.Error("changing the time precision must change the filter")
+	}
+
+	updated = current
+	updated.Values = []lib.Value{current.Values[0], {Name: "b", Type: "string", Path: "value.c", Tag: true}}
+	if !exportFilterChanged(current, updated) {
+		t.Error("changing the path of a value must change the filter")
+	}
+	updated.Values = []lib.Value{current.Values[0], {Name: "b", Type: "string", Path: "value.b", Tag: true, PrimaryKey: true}}
+	if !exportFilterChanged(current, updated) {
+		t.Error("adding a value to the primary key must change the filter")
+	}
+	updated.Values = current.Values[:1]
+	if !exportFilterChanged(current, updated) {
+		t.Error("removing a value must change the filter")
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/service"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/service/tests/docker"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/service/tests/mocks"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
+)
+
+func TestApplyTwice(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, dbIp, _, err := docker.MySqlWithNetwork(ctx, wg, "apply")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = db.Init(&config.MySQLConfig{Host: dbIp, Port: 3306, User: "usr", Password: "pw", Database: "apply"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	db.NewMigration(db.GetDB(), "").Migrate()
+
+	permV2, err := client.NewTestClient(ctx)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	serving, err := service.NewServing(mocks.Driver{}, mocks.Influx{}, mocks.Timescale{}, mocks.Pipeline{}, mocks.Imports{}, nil,
+		"", permV2, "", 0, "", 0, "", 0, config.JobConfig{}, ctx, wg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	export := func(name string, offset string) lib.BundleExport {
+		return lib.BundleExport{
+			ExportDatabase: "apply-db",
+			ServingRequest: lib.ServingRequest{
+				FilterType:  "operatorId",
+				Filter:      "pipeline:operator",
+				Name:        name,
+				EntityName:  "entity",
+				ServiceName: "service",
+				Topic:       "topic",
+				TimePath:    "time",
+				Offset:      offset,
+				Values:      []lib.ServingRequestValue{{Name: "value", Type: "float", Path: "value"}},
+			},
+		}
+	}
+	bundle := lib.Bundle{
+		ExportDatabases: []lib.ExportDatabaseRequest{{
+			Name:          "apply-db",
+			Type:          "timescaledb",
+			Deployment:    "external",
+			Url:           "postgres://localhost:5432/exports",
+			EwFilterTopic: "filter",
+		}},
+		Exports: []lib.BundleExport{export("relative", "7d"), export("legacy", "largest"), export("timestamp", "2025-01-01T01:00:00+01:00")},
+	}
+
+	first, err := serving.Apply(bundle, nil, false, false, TestTokenUser, TestToken)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, action := range first.Actions {
+		if action.Action != lib.ApplyActionCreate || !action.Applied || action.Error != "" {
+			t.Errorf("unexpected first apply action %+v", action)
+		}
+	}
+
+	second, err := serving.Apply(bundle, nil, false, false, TestTokenUser, TestToken)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(second.Actions) != len(bundle.Exports) {
+		t.Errorf("expected one action per export, got %+v", second.Actions)
+	}
+	for _, action := range second.Actions {
+		if action.Kind != lib.ApplyKindExport || action.Action != lib.ApplyActionNone || len(action.Changes) > 0 {
+			t.Errorf("applying the same bundle again changed %s: %+v", action.Name, action)
+		}
+	}
+}
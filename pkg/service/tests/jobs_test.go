@@ -0,0 +1,142 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/service/tests/docker"
+	"github.com/google/uuid"
+)
+
+func TestJobLease(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, dbIp, _, err := docker.MySqlWithNetwork(ctx, wg, "jobs")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = db.Init(&config.MySQLConfig{Host: dbIp, Port: 3306, User: "usr", Password: "pw", Database: "jobs"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	db.NewMigration(db.GetDB(), "").Migrate()
+
+	instanceA := uuid.NewString()
+	instanceB := uuid.NewString()
+	var jobs []lib.Job
+	for i, instanceId := range []string{instanceA, instanceA, instanceB} {
+		job := lib.Job{
+			ID:         uuid.New(),
+			Type:       lib.JobTypeUpdate,
+			State:      lib.JobStatePending,
+			InstanceID: instanceId,
+			CreatedAt:  time.Now().Add(time.Duration(i-3) * time.Minute),
+		}
+		err = db.GetDB().Create(&job).Error
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		jobs = append(jobs, job)
+	}
+	claim := func(t *testing.T, workerId string, expected *lib.Job) {
+		job, err := db.ClaimNextJob(workerId, time.Minute)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if expected == nil {
+			if job != nil {
+				t.Error("unexpected job", job.ID)
+			}
+			return
+		}
+		if job == nil || job.ID != expected.ID || job.WorkerId != workerId {
+			t.Error("expected job", expected.ID, "got", job)
+		}
+	}
+
+	t.Run("jobs of an export run one after another", func(t *testing.T) {
+		claim(t, "w1", &jobs[0])
+		claim(t, "w2", &jobs[2])
+		claim(t, "w2", nil)
+	})
+
+	t.Run("jobs with a valid lease are not claimed again", func(t *testing.T) {
+		leased, err := db.ExtendJobLease(jobs[0].ID, "w1", time.Minute)
+		if err != nil || !leased {
+			t.Error("expected lease to be extended", err)
+		}
+		claim(t, "w3", nil)
+	})
+
+	t.Run("jobs with an expired lease are claimed again", func(t *testing.T) {
+		err = db.GetDB().Model(&lib.Job{}).Where("id = ?", jobs[0].ID).UpdateColumn("lease_until", time.Now().Add(-time.Second)).Error
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		claim(t, "w3", &jobs[0])
+		leased, err := db.ExtendJobLease(jobs[0].ID, "w1", time.Minute)
+		if err != nil || leased {
+			t.Error("expected lease of previous worker to be lost", err)
+		}
+		finished, err := db.FinishJob(jobs[0].ID, "w1", nil)
+		if err != nil || finished {
+			t.Error("expected result of previous worker to be discarded", err)
+		}
+	})
+
+	t.Run("finished jobs release the export", func(t *testing.T) {
+		finished, err := db.FinishJob(jobs[0].ID, "w3", nil)
+		if err != nil || !finished {
+			t.Error("expected job to be finished", err)
+		}
+		claim(t, "w1", &jobs[1])
+		var job lib.Job
+		err = db.GetDB().Where("id = ?", jobs[0].ID).First(&job).Error
+		if err != nil || job.State != lib.JobStateDone || job.LeaseUntil != nil {
+			t.Error("unexpected job", job, err)
+		}
+	})
+
+	t.Run("finished jobs are removed after the retention", func(t *testing.T) {
+		err = db.DeleteFinishedJobs(time.Now().Add(time.Second))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var count int
+		err = db.GetDB().Model(&lib.Job{}).Count(&count).Error
+		if err != nil || count != 2 {
+			t.Error("expected only running jobs to remain", count, err)
+		}
+	})
+}
@@ -19,14 +19,15 @@ package mocks
 import (
 	"github.com/SENERGY-Platform/analytics-serving/lib"
 	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
 )
 
 type Driver struct{}
 
-func (this Driver) CreateInstance(instance *lib.Instance, dataFields string, tagFields string) (serviceId string, err error) {
+func (this Driver) CreateInstance(tx *gorm.DB, instance *lib.Instance, dataFields string, tagFields string) (serviceId string, err error) {
 	return uuid.NewString(), nil
 }
 
-func (this Driver) DeleteInstance(instance *lib.Instance) (err error) {
+func (this Driver) DeleteInstance(tx *gorm.DB, instance *lib.Instance) (err error) {
 	return nil
 }
@@ -25,3 +25,11 @@ type Influx struct{}
 func (i Influx) ForceDeleteMeasurement(id string, userId string, instance lib.Instance) (errs []error) {
 	return nil
 }
+
+func (i Influx) Ping(database lib.ExportDatabase) error {
+	return nil
+}
+
+func (i Influx) CopyMeasurement(from lib.Instance, to lib.Instance, progress func(percent int)) error {
+	return nil
+}
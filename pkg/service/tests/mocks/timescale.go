@@ -0,0 +1,43 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mocks
+
+import (
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+)
+
+type Timescale struct{}
+
+func (t Timescale) DropTable(instance lib.Instance) (errs []error) {
+	return nil
+}
+
+func (t Timescale) TruncateTable(instance lib.Instance) (errs []error) {
+	return nil
+}
+
+func (t Timescale) Ping(database lib.ExportDatabase) error {
+	return nil
+}
+
+func (t Timescale) HasExtension(database lib.ExportDatabase, name string) (bool, error) {
+	return true, nil
+}
+
+func (t Timescale) CopyTable(from lib.Instance, to lib.Instance, progress func(percent int)) error {
+	return nil
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/db"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/service/tests/docker"
+)
+
+func TestOutboxFailingHead(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, dbIp, _, err := docker.MySqlWithNetwork(ctx, wg, "outbox")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = db.Init(&config.MySQLConfig{Host: dbIp, Port: 3306, User: "usr", Password: "pw", Database: "outbox"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+	db.NewMigration(db.GetDB(), "").Migrate()
+
+	for _, key := range []string{"a", "b", "a", "c"} {
+		err = db.AddOutboxMessage(db.GetDB(), &db.OutboxMessage{Topic: "filters", Key: key, Payload: key})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	pendingKeys := func() (keys []string) {
+		messages, err := db.GetPendingOutboxMessages(10)
+		if err != nil {
+			t.Error(err)
+		}
+		for _, message := range messages {
+			keys = append(keys, message.Key)
+		}
+		return
+	}
+	messages, err := db.GetPendingOutboxMessages(10)
+	if err != nil || len(messages) != 4 {
+		t.Error("expected all messages to be pending", messages, err)
+		return
+	}
+	head := messages[0]
+
+	t.Run("failed head waits for retry", func(t *testing.T) {
+		err = db.SetOutboxMessageError(head, errors.New("unknown topic"), 3, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if keys := pendingKeys(); len(keys) != 2 || keys[0] != "b" || keys[1] != "c" {
+			t.Error("expected only messages of other keys", keys)
+		}
+	})
+
+	t.Run("due head is retried", func(t *testing.T) {
+		head.Attempts = 1
+		err = db.SetOutboxMessageError(head, errors.New("unknown topic"), 3, time.Now().Add(-time.Second))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if keys := pendingKeys(); len(keys) != 4 {
+			t.Error("expected all messages", keys)
+		}
+	})
+
+	t.Run("parked head releases its key", func(t *testing.T) {
+		head.Attempts = 2
+		err = db.SetOutboxMessageError(head, errors.New("unknown topic"), 3, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if keys := pendingKeys(); len(keys) != 3 || keys[0] != "b" || keys[1] != "a" || keys[2] != "c" {
+			t.Error("expected all other messages", keys)
+		}
+	})
+
+	t.Run("parked messages are removed after retention", func(t *testing.T) {
+		err = db.DeleteSentOutboxMessages(time.Now().Add(time.Second))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var count int
+		err = db.GetDB().Model(&db.OutboxMessage{}).Count(&count).Error
+		if err != nil || count != 3 {
+			t.Error("expected parked message to be removed", count, err)
+		}
+	})
+
+	t.Run("delivery of the latest instance message", func(t *testing.T) {
+		message := db.OutboxMessage{Topic: "filters", Key: "d", InstanceID: "d", Payload: "d"}
+		err = db.AddOutboxMessage(db.GetDB(), &message)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if delivered, err := db.OutboxDelivered("d"); err != nil || delivered {
+			t.Error("expected pending message", delivered, err)
+		}
+		err = db.MarkOutboxMessagesSent([]db.OutboxMessage{message}, "put")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if delivered, err := db.OutboxDelivered("d"); err != nil || !delivered {
+			t.Error("expected sent message", delivered, err)
+		}
+		message = db.OutboxMessage{Topic: "filters", Key: "d", InstanceID: "d", Payload: "d"}
+		err = db.AddOutboxMessage(db.GetDB(), &message)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = db.SetOutboxMessageError(message, errors.New("unknown topic"), 1, time.Now())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, err := db.OutboxDelivered("d"); err == nil {
+			t.Error("expected error for parked message")
+		}
+		if delivered, err := db.OutboxDelivered("unknown"); err != nil || !delivered {
+			t.Error("expected instance without messages to be delivered", delivered, err)
+		}
+	})
+}
@@ -72,6 +72,7 @@ func TestPermissionsV2Handling(t *testing.T) {
 
 	t.Setenv("CLEANUP_WAIT_DURATION", "5s")
 	t.Setenv("CLEANUP_CRON", "0 3 * * *")
+	t.Setenv("PURGE_DELAY", "0s")
 	t.Setenv("SERVER_PORT", serverPort)
 	t.Setenv("EXPORT_DATABASE_ID_PREFIX", "")
 
@@ -128,13 +129,16 @@ func TestPermissionsV2Handling(t *testing.T) {
 	var driver service.Driver
 	var pipeline service.PipelineApiService
 	var imp service.ImportDeployService
+	var notifier service.NotificationService
 	var influx service.Influx
+	var timescale service.Timescale
 	driver = mocks.Driver{}
 	pipeline = mocks.Pipeline{}
 	imp = mocks.Imports{}
 	influx = mocks.Influx{}
+	timescale = mocks.Timescale{}
 
-	httpHandler, err := api.CreateServer(cfg, &driver, &pipeline, &imp, &permV2, &influx)
+	httpHandler, err := api.CreateServer(cfg, &driver, &pipeline, &imp, &notifier, &permV2, &influx, &timescale, ctx, wg)
 	if err != nil {
 		util.Logger.Error("error creating http engine", "error", err)
 		return
@@ -147,12 +151,21 @@ func TestPermissionsV2Handling(t *testing.T) {
 	}
 	serving, err := service.NewServing(driver,
 		influx,
+		timescale,
 		pipeline,
 		imp,
+		notifier,
 		cfg.ExportDatabaseIdPrefix,
 		permV2,
 		cfg.CleanupConfig.Cron,
 		cleanupWait,
+		cfg.CleanupConfig.PurgeCron,
+		0,
+		cfg.CleanupConfig.ExpiryCron,
+		0,
+		cfg.JobConfig,
+		ctx,
+		wg,
 	)
 	if err != nil {
 		t.Error(err)
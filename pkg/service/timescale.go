@@ -0,0 +1,323 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/analytics-serving/lib"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/config"
+	"github.com/SENERGY-Platform/analytics-serving/pkg/util"
+	"github.com/lib/pq"
+)
+
+const (
+	TimescaleDeleteModeDrop    = "drop"
+	TimescaleDeleteModeArchive = "archive"
+)
+
+type Timescale interface {
+	DropTable(instance lib.Instance) (errs []error)
+	TruncateTable(instance lib.Instance) (errs []error)
+	Ping(database lib.ExportDatabase) error
+	HasExtension(database lib.ExportDatabase, name string) (bool, error)
+	CopyTable(from lib.Instance, to lib.Instance, progress func(percent int)) error
+}
+
+// TimescaleImpl removes the tables of timescale and postgres exports. Connections are pooled per
+// export database url, the configured url is used for internal export databases without one.
+type TimescaleImpl struct {
+	cfg   config.TimescaleConfig
+	conns map[string]*sql.DB
+	mux   sync.Mutex
+}
+
+func NewTimescale(cfg config.TimescaleConfig, ctx context.Context, wg *sync.WaitGroup) *TimescaleImpl {
+	util.Logger.Info("init timescale connection pool")
+	impl := &TimescaleImpl{
+		cfg:   cfg,
+		conns: map[string]*sql.DB{},
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		impl.close()
+	}()
+	return impl
+}
+
+func (t *TimescaleImpl) close() {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	for key, conn := range t.conns {
+		if err := conn.Close(); err != nil {
+			util.Logger.Error("could not close timescale connection", "error", err)
+		}
+		delete(t.conns, key)
+	}
+	util.Logger.Info("closed timescale connections")
+}
+
+func (t *TimescaleImpl) getConn(database lib.ExportDatabase) (conn *sql.DB, err error) {
+	dsn := database.Url
+	//only internal export databases may use the configured server
+	if dsn == "" && database.Deployment == DeploymentInternal {
+		dsn = t.cfg.Url
+	}
+	if dsn == "" {
+		return nil, errors.New("missing timescale connection url for export-database " + database.ID)
+	}
+	if database.Url != "" {
+		database, err = withCredentials(database)
+		if err != nil {
+			return nil, err
+		}
+		dsn = withDsnCredentials(dsn, *database.Credentials)
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	conn, ok := t.conns[dsn]
+	if ok {
+		return conn, nil
+	}
+	conn, err = sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	t.conns[dsn] = conn
+	return conn, nil
+}
+
+// DropTable drops the table of an export or, in archive mode, moves it to the archive schema.
+func (t *TimescaleImpl) DropTable(instance lib.Instance) (errs []error) {
+	table, err := util.TableName(instance.ID.String(), instance.Database)
+	if err != nil {
+		return []error{err}
+	}
+	conn, err := t.getConn(instance.ExportDatabase)
+	if err != nil {
+		return []error{err}
+	}
+	ctx, cf := context.WithTimeout(context.Background(), time.Minute)
+	defer cf()
+	switch t.cfg.DeleteMode {
+	case TimescaleDeleteModeArchive:
+		schema := pq.QuoteIdentifier(t.cfg.ArchiveSchema)
+		_, err = conn.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+schema)
+		if err == nil {
+			_, err = conn.ExecContext(ctx, "ALTER TABLE IF EXISTS "+pq.QuoteIdentifier(table)+" SET SCHEMA "+schema)
+		}
+	case TimescaleDeleteModeDrop, "":
+		_, err = conn.ExecContext(ctx, "DROP TABLE IF EXISTS "+pq.QuoteIdentifier(table)+" CASCADE")
+	default:
+		err = fmt.Errorf("unknown timescale delete mode '%s'", t.cfg.DeleteMode)
+	}
+	if err != nil {
+		errs = append(errs, errors.New("removing table '"+table+"' failed - "+err.Error()))
+	}
+	return
+}
+
+// TruncateTable removes all rows of an export table. Missing tables are ignored.
+func (t *TimescaleImpl) TruncateTable(instance lib.Instance) (errs []error) {
+	table, err := util.TableName(instance.ID.String(), instance.Database)
+	if err != nil {
+		return []error{err}
+	}
+	conn, err := t.getConn(instance.ExportDatabase)
+	if err != nil {
+		return []error{err}
+	}
+	ctx, cf := context.WithTimeout(context.Background(), time.Minute)
+	defer cf()
+	_, err = conn.ExecContext(ctx, "TRUNCATE TABLE "+pq.QuoteIdentifier(table))
+	var pqErr *pq.Error
+	if err != nil && !(errors.As(err, &pqErr) && pqErr.Code == "42P01") {
+		errs = append(errs, errors.New("truncating table '"+table+"' failed - "+err.Error()))
+	}
+	return
+}
+
+func (t *TimescaleImpl) Ping(database lib.ExportDatabase) error {
+	conn, err := t.getConn(database)
+	if err != nil {
+		return err
+	}
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	return conn.PingContext(ctx)
+}
+
+func (t *TimescaleImpl) HasExtension(database lib.ExportDatabase, name string) (exists bool, err error) {
+	conn, err := t.getConn(database)
+	if err != nil {
+		return
+	}
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	err = conn.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pg_extension WHERE extname = $1)", name).Scan(&exists)
+	return
+}
+
+// CopyTable inserts all rows of the export table of from into the table of to. Only columns present in both tables are
+// copied and rows violating a unique constraint of the target are skipped. The target table is created by the export
+// worker, so it is awaited for a while.
+func (t *TimescaleImpl) CopyTable(from lib.Instance, to lib.Instance, progress func(percent int)) (err error) {
+	table, err := util.TableName(from.ID.String(), from.Database)
+	if err != nil {
+		return
+	}
+	src, err := t.getConn(from.ExportDatabase)
+	if err != nil {
+		return
+	}
+	dst, err := t.getConn(to.ExportDatabase)
+	if err != nil {
+		return
+	}
+	var dstColumns []string
+	err = util.Retry(12, 5*time.Second, func() (err error) {
+		dstColumns, err = tableColumns(dst, table)
+		if err == nil && len(dstColumns) == 0 {
+			err = errors.New("table '" + table + "' does not exist in target export-database")
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	srcColumns, err := tableColumns(src, table)
+	if err != nil || len(srcColumns) == 0 {
+		//nothing to copy
+		return
+	}
+	var columns []string
+	for _, column := range srcColumns {
+		if util.StringInSlice(column, dstColumns) {
+			columns = append(columns, pq.QuoteIdentifier(column))
+		}
+	}
+	if len(columns) == 0 {
+		return errors.New("tables '" + table + "' have no columns in common")
+	}
+	var total int
+	err = src.QueryRow("SELECT count(*) FROM " + pq.QuoteIdentifier(table)).Scan(&total)
+	if err != nil || total == 0 {
+		return
+	}
+	rows, err := src.Query("SELECT " + strings.Join(columns, ", ") + " FROM " + pq.QuoteIdentifier(table))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	//postgres allows at most 65535 parameters per statement
+	batchSize := min(1000, 65535/len(columns))
+	insert := "INSERT INTO " + pq.QuoteIdentifier(table) + " (" + strings.Join(columns, ", ") + ") VALUES "
+	var batch [][]interface{}
+	copied := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		var placeholders []string
+		var args []interface{}
+		for _, row := range batch {
+			var params []string
+			for _, value := range row {
+				args = append(args, value)
+				params = append(params, "$"+strconv.Itoa(len(args)))
+			}
+			placeholders = append(placeholders, "("+strings.Join(params, ", ")+")")
+		}
+		ctx, cf := context.WithTimeout(context.Background(), time.Minute)
+		defer cf()
+		if _, err := dst.ExecContext(ctx, insert+strings.Join(placeholders, ", ")+" ON CONFLICT DO NOTHING", args...); err != nil {
+			return errors.New("copying rows of table '" + table + "' failed - " + err.Error())
+		}
+		copied += len(batch)
+		batch = batch[:0]
+		progress(min(99, copied*100/total))
+		return nil
+	}
+	for rows.Next() {
+		row := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err = rows.Scan(pointers...); err != nil {
+			return
+		}
+		for i, value := range row {
+			//jsonb is read as bytes but would be written as bytea
+			if b, ok := value.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+		batch = append(batch, row)
+		if len(batch) >= batchSize {
+			if err = flush(); err != nil {
+				return
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+	return flush()
+}
+
+func tableColumns(conn *sql.DB, table string) (columns []string, err error) {
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	rows, err := conn.QueryContext(ctx, "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position", table)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column string
+		if err = rows.Scan(&column); err != nil {
+			return
+		}
+		columns = append(columns, column)
+	}
+	err = rows.Err()
+	return
+}
+
+// withDsnCredentials adds stored credentials to an url or key/value connection string.
+func withDsnCredentials(dsn string, credentials lib.Credentials) string {
+	if credentials.Username == "" {
+		return dsn
+	}
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		u.User = url.UserPassword(credentials.Username, credentials.Password)
+		return u.String()
+	}
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return dsn + " user='" + quote.Replace(credentials.Username) + "' password='" + quote.Replace(credentials.Password) + "'"
+}
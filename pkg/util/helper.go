@@ -17,10 +17,26 @@
 package util
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	OffsetEarliest = "earliest"
+	OffsetLatest   = "latest"
+)
+
+// legacyOffsets maps the kafka offset names accepted before start offsets were validated.
+var legacyOffsets = map[string]string{"smallest": OffsetEarliest, "largest": OffsetLatest}
+
+var relativeOffsetDays = regexp.MustCompile(`^(\d+)([dw])$`)
+
 func StringInSlice(str string, slice []string) bool {
 	for _, s := range slice {
 		if str == s {
@@ -46,3 +62,79 @@ func Retry(attempts int, sleep time.Duration, f func() error) (err error) {
 	}
 	return fmt.Errorf("after %d attempts, last error: %s", attempts, err)
 }
+
+// TableName returns the name of the table a timescale or postgres export writes to.
+func TableName(exportID string, dbName string) (string, error) {
+	shortExportID, err := shortenId(exportID)
+	if err != nil {
+		return "", err
+	}
+	shortDBName, err := shortenId(dbName)
+	if err != nil {
+		return "", err
+	}
+	return "userid:" + shortDBName + "_export:" + shortExportID, nil
+}
+
+// ResolveOffset returns the start offset of an export as "earliest", "latest" or a RFC3339 timestamp. Relative
+// offsets like "7d", "2w" or "12h" (optionally prefixed with "-") are resolved as that long before now, the legacy
+// names "smallest" and "largest" as "earliest" and "latest".
+func ResolveOffset(offset string, now time.Time) (string, error) {
+	if offset == OffsetEarliest || offset == OffsetLatest {
+		return offset, nil
+	}
+	if legacy, ok := legacyOffsets[offset]; ok {
+		return legacy, nil
+	}
+	if t, err := time.Parse(time.RFC3339, offset); err == nil {
+		return t.UTC().Format(time.RFC3339), nil
+	}
+	relative := strings.TrimPrefix(offset, "-")
+	var d time.Duration
+	if m := relativeOffsetDays.FindStringSubmatch(relative); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return "", err
+		}
+		d = time.Duration(n) * 24 * time.Hour
+		if m[2] == "w" {
+			d *= 7
+		}
+	} else {
+		var err error
+		d, err = time.ParseDuration(relative)
+		if err != nil {
+			return "", errors.New("invalid offset '" + offset + "'")
+		}
+	}
+	if d <= 0 {
+		return "", errors.New("invalid offset '" + offset + "'")
+	}
+	return now.Add(-d).UTC().Format(time.RFC3339), nil
+}
+
+// IsRelativeOffset reports whether offset is a valid relative duration, which resolves differently every time.
+func IsRelativeOffset(offset string) bool {
+	if offset == OffsetEarliest || offset == OffsetLatest {
+		return false
+	}
+	if _, ok := legacyOffsets[offset]; ok {
+		return false
+	}
+	if _, err := time.Parse(time.RFC3339, offset); err == nil {
+		return false
+	}
+	_, err := ResolveOffset(offset, time.Now())
+	return err == nil
+}
+
+func shortenId(longId string) (string, error) {
+	parts := strings.Split(longId, ":")
+	noPrefix := parts[len(parts)-1]
+	noPrefix = strings.ReplaceAll(noPrefix, "-", "")
+	bytes, err := hex.DecodeString(noPrefix)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2025 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveOffset(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	tests := []struct {
+		offset   string
+		expected string
+		err      bool
+	}{
+		{offset: "earliest", expected: "earliest"},
+		{offset: "latest", expected: "latest"},
+		{offset: "smallest", expected: "earliest"},
+		{offset: "largest", expected: "latest"},
+		{offset: "2025-01-01T00:00:00Z", expected: "2025-01-01T00:00:00Z"},
+		{offset: "2025-01-01T01:00:00+01:00", expected: "2025-01-01T00:00:00Z"},
+		{offset: "7d", expected: "2025-03-08T11:00:00Z"},
+		{offset: "-7d", expected: "2025-03-08T11:00:00Z"},
+		{offset: "2w", expected: "2025-03-01T11:00:00Z"},
+		{offset: "12h", expected: "2025-03-14T23:00:00Z"},
+		{offset: "90m", expected: "2025-03-15T09:30:00Z"},
+		{offset: "", err: true},
+		{offset: "0d", err: true},
+		{offset: "0s", err: true},
+		{offset: "7x", err: true},
+		{offset: "2025-01-01", err: true},
+		{offset: "beginning", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.offset, func(t *testing.T) {
+			result, err := ResolveOffset(test.offset, now)
+			if test.err {
+				if err == nil {
+					t.Error("expected error, got", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if result != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestIsRelativeOffset(t *testing.T) {
+	for _, offset := range []string{"7d", "-2w", "12h"} {
+		if !IsRelativeOffset(offset) {
+			t.Error("expected relative offset", offset)
+		}
+	}
+	for _, offset := range []string{"earliest", "largest", "2025-01-01T00:00:00Z", "7x", ""} {
+		if IsRelativeOffset(offset) {
+			t.Error("unexpected relative offset", offset)
+		}
+	}
+}